@@ -4,11 +4,12 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/go-errors/errors"
-	log "github.com/Sirupsen/logrus"
 )
 
 type Actor struct {
@@ -21,8 +22,44 @@ type Actor struct {
 	// because methods like call(), stop() will be called in another thread
 	aliveLock sync.Mutex
 	alive     bool
-
-	shutdownCh chan bool
+	// hibernated and inflight are protected with aliveLock as well. inflight
+	// counts the senders that are about to put a message into the queue.
+	hibernated bool
+	inflight   int
+	// released is signalled when the last sender is done with the queue of a
+	// stopped actor
+	released chan struct{}
+	// remoteQueued counts the messages from other nodes in the queue. It is
+	// updated atomically.
+	remoteQueued int64
+
+	shutdownCh chan error
+	// systemCh receives calls of the director handled ahead of the queue
+	systemCh chan *systemCall
+	// suspended is only changed within the actor thread, under aliveLock.
+	// deferred holds the messages received while suspended. It is not capped:
+	// messages of other nodes are bounded by WithMaxRemoteQueued as they count
+	// until handled, local casts are not bounded.
+	suspended bool
+	deferred  []*ActorCall
+	// terminated is closed once Terminate returned
+	terminated chan struct{}
+
+	timers actorTimers
+	idle   actorIdle
+
+	// identity is set for activations of virtual actors
+	identity Identity
+
+	// span is the span of the message being handled, only used in the actor
+	// goroutine
+	span Span
+
+	// Introspection: current holds the function being handled and processed
+	// counts the handled messages atomically
+	startedAt time.Time
+	current   atomic.Value
+	processed int64
 }
 
 const kActorQueueLength int = 1
@@ -32,7 +69,7 @@ func (r *Actor) Self() Pid {
 }
 
 // call method synchronously calls function in the actor's thread.
-func (r *Actor) call(function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
+func (r *Actor) call(span SpanContext, function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
 	r.aliveLock.Lock()
 	if !r.alive {
 		r.aliveLock.Unlock()
@@ -41,7 +78,7 @@ func (r *Actor) call(function interface{}, args ...interface{}) ([]interface{},
 	r.aliveLock.Unlock()
 
 	done := make(chan *ActorCall, 0)
-	r.cast(done, function, args...)
+	r.cast(span, done, function, args...)
 	response, ok := <-done
 	if !ok {
 		return nil, ErrActorDied
@@ -53,7 +90,25 @@ func (r *Actor) call(function interface{}, args ...interface{}) ([]interface{},
 // callWithContext function make an assumption that receive function's first argument is context
 func (r *Actor) callWithContext(function interface{}, ctx context.Context, args ...interface{}) ([]interface{}, *DirectorError) {
 	args = append([]interface{}{ctx}, args...)
-	return r.call(function, args...)
+	return r.call(SpanFromContext(ctx), function, args...)
+}
+
+// clock returns the clock of the director or SystemClock for actors started
+// without a director.
+func (r *Actor) clock() Clock {
+	if r.director != nil {
+		return r.director.clock
+	}
+	return SystemClock
+}
+
+// logger returns the logger of the director or the default logger for actors
+// started without a director.
+func (r *Actor) logger() Logger {
+	if r.director != nil {
+		return r.director.logger
+	}
+	return defaultLogger()
 }
 
 // getActor used by Director
@@ -97,20 +152,47 @@ func (r *Actor) verifyCallSignature(function interface{}, args []interface{}) {
 // cast method asynchronously calls function in the actor's thread. This function does
 // not return anything. Errors or panic caused by the function is not passed to the
 // caller.
-func (r *Actor) cast(done chan *ActorCall, function interface{}, args ...interface{}) {
-	r.aliveLock.Lock()
-	if !r.alive {
-		r.aliveLock.Unlock()
+func (r *Actor) cast(span SpanContext, done chan *ActorCall, function interface{}, args ...interface{}) {
+	queue, ok := r.acquireQueue()
+	if !ok {
 		return
 	}
-	r.aliveLock.Unlock()
+	defer r.releaseQueue()
 
 	r.verifyCallSignature(function, args)
-	r.runInThread(done, r.receiver, function, args...)
+	r.runInThread(queue, done, span, r.receiver, function, args...)
+}
+
+// acquireQueue returns the message queue of a live actor and wakes the actor up
+// if it is hibernated. releaseQueue must be called once the message is in the
+// queue.
+func (r *Actor) acquireQueue() (*MessageQueue, bool) {
+	r.aliveLock.Lock()
+	defer r.aliveLock.Unlock()
+	if !r.alive {
+		return nil, false
+	}
+	if r.hibernated {
+		r.wake()
+	}
+	r.inflight += 1
+	return r.queue, true
+}
+
+func (r *Actor) releaseQueue() {
+	r.aliveLock.Lock()
+	r.inflight -= 1
+	if !r.alive && r.inflight == 0 {
+		select {
+		case r.released <- struct{}{}:
+		default:
+		}
+	}
+	r.aliveLock.Unlock()
 }
 
-func (r *Actor) runInThread(done chan *ActorCall, receiver reflect.Value, function interface{}, args ...interface{}) {
-	if r.queue == nil {
+func (r *Actor) runInThread(queue *MessageQueue, done chan *ActorCall, span SpanContext, receiver reflect.Value, function interface{}, args ...interface{}) {
+	if queue == nil {
 		panic("Call startMessageLoop before sending it messages!")
 	}
 
@@ -122,11 +204,31 @@ func (r *Actor) runInThread(done chan *ActorCall, receiver reflect.Value, functi
 		valuedArgs[i+1] = reflect.ValueOf(x)
 	}
 
-	r.queue.In <- &ActorCall{reflect.ValueOf(function), valuedArgs, nil, done}
+	queue.Push(&ActorCall{reflect.ValueOf(function), valuedArgs, nil, done, span})
 }
 
 func (r *Actor) processOneRequest(request *ActorCall) {
-	request.Reply = request.Function.Call(request.Args)
+	r.current.Store(request.Function)
+	defer r.current.Store(reflect.Value{})
+	defer atomic.AddInt64(&r.processed, 1)
+	metrics := r.metrics()
+	_, measured := metrics.(noMetrics)
+	measured = !measured
+	if !measured && !isTracing(r.tracer()) {
+		request.Reply = request.Function.Call(request.Args)
+	} else {
+		method := functionName(request.Function)
+		r.span = r.startHandlerSpan(request, method)
+		start := r.clock().Now()
+		request.Reply = request.Function.Call(request.Args)
+		r.span.End()
+		r.span = nil
+		if measured {
+			labels := Labels{"method": method}
+			metrics.Add(kMetricMessages, labels, 1)
+			metrics.Observe(kMetricHandlerSeconds, labels, r.clock().Now().Sub(start).Seconds())
+		}
+	}
 	if request.Done != nil {
 		request.Done <- request
 	}
@@ -137,13 +239,58 @@ func (r *Actor) terminateActor(errReason error) {
 	if r.director != nil {
 		r.director.removeActor(r.pid)
 	}
+	r.cancelAllTimers()
+	r.stopIdleTimer()
 
 	r.aliveLock.Lock()
 	r.alive = false
-	r.queue.Stop <- true
 	r.aliveLock.Unlock()
+	for _, call := range r.deferred {
+		r.queue.Received()
+		if call.Done != nil {
+			close(call.Done)
+		}
+	}
+	r.deferred = nil
+	r.drainQueue()
+	r.queue.Stop <- true
 
 	r.receiver.Interface().(ActorImplementor).Terminate(errReason)
+
+	r.metrics().Add(kMetricActorsStopped, nil, 1)
+	if _, ok := errReason.(*PanicError); ok {
+		r.metrics().Add(kMetricActorsPanicked, nil, 1)
+	}
+
+	if r.director != nil {
+		r.director.notifyExit(r.pid, errReason)
+	}
+	close(r.terminated)
+}
+
+// drainQueue fails the messages of senders that got the queue before the
+// actor stopped, until none is left. Must be called within the actor thread
+// once alive is false.
+func (r *Actor) drainQueue() {
+	for {
+		r.aliveLock.Lock()
+		pending := r.inflight > 0
+		r.aliveLock.Unlock()
+		if !pending && r.queue.Len() == 0 {
+			return
+		}
+		select {
+		case call := <-r.queue.Out:
+			r.queue.Received()
+			if call.Done != nil {
+				close(call.Done)
+			}
+		case call := <-r.systemCh:
+			call.fn()
+			close(call.done)
+		case <-r.released:
+		}
+	}
 }
 
 func (r *Actor) messageLoop() {
@@ -157,26 +304,84 @@ func (r *Actor) messageLoop() {
 			errPanic := &PanicError{PanicErr: panicErr}
 
 			stacktrace := panicErr.ErrorStack()
-			log.Errorf("actor panic: %s\n", stacktrace)
+			r.logger().Errorf("actor panic: %s\n", stacktrace)
+			if r.director != nil {
+				report := CrashReport{
+					Pid:    r.pid,
+					Type:   r.receiver.Type().String(),
+					Time:   r.clock().Now(),
+					Reason: errPanic.Error(),
+					Stack:  stacktrace,
+				}
+				if lastCall != nil {
+					report.Method = functionName(lastCall.Function)
+				}
+				r.director.recordCrash(report)
+			}
+			if r.span != nil {
+				r.span.SetError(errPanic)
+				r.span.End()
+				r.span = nil
+			}
 
 			r.terminateActor(errPanic)
-			if lastCall != nil {
+			if lastCall != nil && lastCall.Done != nil {
 				close(lastCall.Done)
 			}
 		}
 	}()
 
+	handle := func(call *ActorCall) {
+		r.queue.Received()
+		r.metrics().Observe(kMetricMailboxDepth, nil, float64(r.queue.Len()))
+		lastCall = call
+		r.idle.lastActive = r.clock().Now()
+		r.processOneRequest(call)
+		lastCall = nil
+	}
+
+	r.startIdleTimer()
+
 ForLoop:
 	for {
+		if !r.suspended && len(r.deferred) > 0 {
+			// Resumed, the messages received while suspended come first but
+			// system messages are still handled in between
+			select {
+			case reason := <-r.shutdownCh:
+				r.terminateActor(reason)
+			case call := <-r.systemCh:
+				call.fn()
+				close(call.done)
+			default:
+				call := r.deferred[0]
+				r.deferred = r.deferred[1:]
+				handle(call)
+			}
+			continue
+		}
+
 		select {
 		case call, ok := <-r.queue.Out:
 			if !ok {
 				break ForLoop
 			}
-			lastCall = call
-			r.processOneRequest(call)
-		case <-r.shutdownCh:
-			r.terminateActor(ErrActorStop)
+			if r.suspended {
+				// Still counted by the queue until it is handled
+				r.deferred = append(r.deferred, call)
+				continue
+			}
+			handle(call)
+		case reason := <-r.shutdownCh:
+			r.terminateActor(reason)
+		case call := <-r.systemCh:
+			call.fn()
+			close(call.done)
+		case <-r.idle.ch:
+			if r.checkIdle() {
+				// Hibernated, the goroutine is released until the next message
+				return
+			}
 		}
 	}
 }
@@ -187,7 +392,11 @@ func (r *Actor) startMessageLoop(receiver interface{}) {
 	r.queue = NewMessageQueue(kActorQueueLength)
 	r.receiver = reflect.ValueOf(receiver)
 	// Make this buffered so the actor can self stop
-	r.shutdownCh = make(chan bool, 1)
+	r.shutdownCh = make(chan error, 1)
+	r.systemCh = make(chan *systemCall)
+	r.released = make(chan struct{}, 1)
+	r.terminated = make(chan struct{})
+	r.idle.ch = make(chan bool, 1)
 
 	r.aliveLock.Lock()
 	r.alive = true
@@ -198,12 +407,20 @@ func (r *Actor) startMessageLoop(receiver interface{}) {
 
 // stop stops the actor thread.
 func (r *Actor) stop() *DirectorError {
+	r.stopWithReason(ErrActorStop)
+	return nil
+}
+
+// stopWithReason stops the actor thread. Terminate is called with reason.
+func (r *Actor) stopWithReason(reason error) {
 	r.aliveLock.Lock()
 	defer r.aliveLock.Unlock()
 	if r.alive {
-		// Pass nil function pointer to stop the message loop
+		// A hibernated actor has no thread to handle the shutdown
+		if r.hibernated {
+			r.wake()
+		}
 		r.alive = false
-		r.shutdownCh <- true
+		r.shutdownCh <- reason
 	}
-	return nil
 }
@@ -93,7 +93,7 @@ func BenchmarkActor(b *testing.B) {
 	defer a.stop()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		a.call((*TestActor).AddX, 3)
+		a.call(SpanContext{}, (*TestActor).AddX, 3)
 	}
 }
 
@@ -102,7 +102,7 @@ func TestAddX(t *testing.T) {
 	a.startMessageLoop(&a)
 	defer a.stop()
 
-	r, err := a.call((*TestActor).AddX, 4)
+	r, err := a.call(SpanContext{}, (*TestActor).AddX, 4)
 	if err != nil {
 		t.Errorf("Expected no error, got %v\n", err)
 	}
@@ -116,7 +116,7 @@ func TestAddX(t *testing.T) {
 	// Stop the actor and see the behaviour after stop
 	a.stop()
 
-	r, err = a.call((*TestActor).AddX, 4)
+	r, err = a.call(SpanContext{}, (*TestActor).AddX, 4)
 	if err != ErrActorStop {
 		t.Errorf("Expected ErrActorStop error, got %v\n", err)
 	}
@@ -126,7 +126,7 @@ func TestAddX(t *testing.T) {
 
 	// cast should success without any errors
 	out := make(chan *ActorCall, 1)
-	a.cast(out, (*TestActor).AddX, 4)
+	a.cast(SpanContext{}, out, (*TestActor).AddX, 4)
 }
 
 func TestPanic(t *testing.T) {
@@ -134,7 +134,7 @@ func TestPanic(t *testing.T) {
 	a.startMessageLoop(&a)
 	defer a.stop()
 
-	_, err := a.call((*TestActor).DoPanic)
+	_, err := a.call(SpanContext{}, (*TestActor).DoPanic)
 	if err != ErrActorDied {
 		t.Errorf("Expected ErrActorDied error, instead got %v\n", err)
 	}
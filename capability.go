@@ -0,0 +1,17 @@
+package cine
+
+// WithCapabilities makes the pids of the director unforgeable. Each pid
+// carries a random token and an actor is only found with the exact pid it
+// was started with, so other nodes can only reach the actors whose pids they
+// were handed, e.g. in a message or by SpawnOn. The pid is revoked when the
+// actor stops.
+func WithCapabilities() Option {
+	return func(d *Director) {
+		d.config.capabilities = true
+	}
+}
+
+// randomToken returns 128 random bits in hex.
+func randomToken() string {
+	return randomId(16)
+}
@@ -0,0 +1,66 @@
+package cine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithCapabilities())
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+	if pid.Token == "" {
+		t.Fatal("Expected pid to carry a token")
+	}
+	if strings.Contains(pid.String(), pid.Token) {
+		t.Errorf("Expected the token not to be printed but got %v\n", pid)
+	}
+	other := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(other)
+	if other.Token == pid.Token {
+		t.Errorf("Expected distinct tokens but got %v twice\n", pid.Token)
+	}
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	guessed := Pid{NodeName: pid.NodeName, ActorId: pid.ActorId, Creation: pid.Creation}
+	if _, err := d.Call(guessed, (*Counter).Incr); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+	forged := pid
+	forged.Token = other.Token
+	if _, err := d.Call(forged, (*Counter).Incr); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+	if err := d.Stop(guessed); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+
+	// Stopping the actor revokes its pid
+	if err := d.Stop(pid); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	waitFor(t, "stop", func() bool {
+		_, err := remoteD.localActorFromPid(pid)
+		return err != nil
+	})
+	if _, err := d.Call(pid, (*Counter).Incr); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+}
+
+func TestNoCapabilities(t *testing.T) {
+	d := newTestDirector(t)
+	pid := d.StartActor(&Counter{})
+	defer d.Stop(pid)
+	if pid.Token != "" {
+		t.Errorf("Expected no token but got %v\n", pid.Token)
+	}
+	if _, err := d.Call(Pid{NodeName: pid.NodeName, ActorId: pid.ActorId, Creation: pid.Creation}, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
@@ -0,0 +1,209 @@
+package cine
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Clock is the source of time of a Director. Every time dependent path
+// (timers, CallWithContext deadlines, remote timeouts) goes through the clock so
+// tests can replace it with a FakeClock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after duration d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by Clock.AfterFunc. *time.Timer implements it.
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the wall clock. It is the default clock of a Director.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// FakeClock is a manual clock for tests. Time only moves when Advance is
+// called. Like time.AfterFunc, timers that become due call their function in
+// its own goroutine, so tests wait for the effects with BlockUntil or by
+// polling.
+type FakeClock struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	when   time.Time
+	f      func()
+	active bool
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTimer{clock: c, f: f}
+	c.schedule(t, d)
+	return t
+}
+
+// Advance moves the clock forward by d and starts the function of every timer
+// that becomes due in its own goroutine. It does not wait for them to return.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].when.After(c.now) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		t.active = false
+		go t.f()
+	}
+	c.cond.Broadcast()
+}
+
+// Pending returns the number of timers waiting to fire.
+func (c *FakeClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire. Tests use it
+// to make sure the code under test reached the point where it waits.
+func (c *FakeClock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// schedule must be called within c.lock critical section
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.when = c.now.Add(d)
+	t.active = true
+	c.timers = append(c.timers, t)
+	// Stable sort keeps timers with the same deadline in creation order
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].when.Before(c.timers[j].when)
+	})
+	c.cond.Broadcast()
+}
+
+// unschedule must be called within c.lock critical section
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+	t.active = false
+	c.cond.Broadcast()
+	return true
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	wasActive := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return wasActive
+}
+
+// clockContext is a context whose deadline is measured by a Clock.
+type clockContext struct {
+	context.Context
+	deadline time.Time
+
+	lock     sync.Mutex
+	timedOut bool
+}
+
+func (c *clockContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockContext) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.timedOut {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// ContextWithDeadline is like context.WithDeadline but the deadline is
+// measured by clock.
+func ContextWithDeadline(clock Clock, parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if clock == SystemClock {
+		return context.WithDeadline(parent, deadline)
+	}
+	if cur, ok := parent.Deadline(); ok && cur.Before(deadline) {
+		deadline = cur
+	}
+	inner, cancel := context.WithCancel(parent)
+	ctx := &clockContext{Context: inner, deadline: deadline}
+	expire := func() {
+		ctx.lock.Lock()
+		if inner.Err() == nil {
+			ctx.timedOut = true
+		}
+		ctx.lock.Unlock()
+		cancel()
+	}
+
+	timeout := deadline.Sub(clock.Now())
+	if timeout <= 0 {
+		expire()
+		return ctx, cancel
+	}
+	t := clock.AfterFunc(timeout, expire)
+	return ctx, func() {
+		t.Stop()
+		cancel()
+	}
+}
+
+// ContextWithTimeout is like context.WithTimeout but the timeout is measured
+// by clock.
+func ContextWithTimeout(clock Clock, parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return ContextWithDeadline(clock, parent, clock.Now().Add(timeout))
+}
@@ -0,0 +1,141 @@
+package cine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func expectFired(t *testing.T, fired chan int, expected int) {
+	select {
+	case x := <-fired:
+		if x != expected {
+			t.Errorf("Expected timer %d to fire but got %d\n", expected, x)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timer %d did not fire\n", expected)
+	}
+}
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewFakeClock(start)
+
+	fired := make(chan int, 3)
+	clock.AfterFunc(2*time.Second, func() { fired <- 2 })
+	clock.AfterFunc(1*time.Second, func() { fired <- 1 })
+	stopped := clock.AfterFunc(1*time.Second, func() { fired <- 3 })
+	if !stopped.Stop() {
+		t.Error("Expected Stop to return true for a pending timer")
+	}
+	if clock.Pending() != 2 {
+		t.Errorf("Expected 2 pending timers but got %d\n", clock.Pending())
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	expectFired(t, fired, 1)
+	if clock.Pending() != 1 {
+		t.Errorf("Expected 1 pending timer but got %d\n", clock.Pending())
+	}
+	if now := clock.Now(); !now.Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("Unexpected clock time %v\n", now)
+	}
+
+	clock.Advance(time.Second)
+	expectFired(t, fired, 2)
+	if stopped.Stop() {
+		t.Error("Expected Stop to return false for a stopped timer")
+	}
+	select {
+	case x := <-fired:
+		t.Errorf("Unexpected timer %d fired\n", x)
+	default:
+	}
+}
+
+func TestFakeClockReset(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	fired := make(chan int, 1)
+	var timer Timer
+	timer = clock.AfterFunc(time.Second, func() {
+		timer.Reset(time.Second)
+		fired <- 1
+	})
+
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		expectFired(t, fired, 1)
+	}
+}
+
+func TestFakeClockAdvanceDoesNotWait(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	var lock sync.Mutex
+	fired := make(chan int, 1)
+	clock.AfterFunc(time.Second, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		fired <- 1
+	})
+
+	// A callback blocked on a lock of the test does not block Advance
+	lock.Lock()
+	clock.Advance(time.Second)
+	lock.Unlock()
+	expectFired(t, fired, 1)
+}
+
+func TestContextWithTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	ctx, cancel := ContextWithTimeout(clock, context.Background(), time.Second)
+	defer cancel()
+
+	if dl, ok := ctx.Deadline(); !ok || !dl.Equal(time.Unix(1, 0)) {
+		t.Errorf("Unexpected deadline %v\n", dl)
+	}
+	clock.Advance(999 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Errorf("Expected no error before deadline but got %v\n", ctx.Err())
+	}
+	clock.Advance(time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected context to be done")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v\n", ctx.Err())
+	}
+
+	ctx, cancel = ContextWithTimeout(clock, context.Background(), time.Second)
+	cancel()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected Canceled but got %v\n", ctx.Err())
+	}
+	if clock.Pending() != 0 {
+		t.Errorf("Expected cancel to stop the timer, %d pending\n", clock.Pending())
+	}
+}
+
+func TestTimerWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	d := &Director{clock: clock}
+	a := &TimerActor{Actor{director: d}, make(chan int, 10), 0}
+	a.startMessageLoop(a)
+	defer a.stop()
+
+	a.Every(time.Minute, (*TimerActor).Tick, 1)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		<-a.ticks
+	}
+	select {
+	case <-a.ticks:
+		t.Error("Unexpected tick")
+	default:
+	}
+}
@@ -0,0 +1,282 @@
+package cine
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"time"
+)
+
+// Codec creates the rpc codecs used on connections between directors. Both
+// ends of a connection must use the same codec.
+type Codec interface {
+	NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec
+	NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec
+}
+
+// GobCodec is the default codec. It is the same wire format as net/rpc.
+type GobCodec struct{}
+
+func (GobCodec) NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &gobServerCodec{conn, gob.NewDecoder(conn), gob.NewEncoder(buf), buf}
+}
+
+func (GobCodec) NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	buf := bufio.NewWriter(conn)
+	return &gobClientCodec{conn, gob.NewDecoder(conn), gob.NewEncoder(buf), buf}
+}
+
+type gobServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+}
+
+func (c *gobServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobServerCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			// The header could not be encoded, the connection is unusable
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobServerCodec) Close() error {
+	return c.rwc.Close()
+}
+
+type gobClientCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+}
+
+func (c *gobClientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobClientCodec) ReadResponseHeader(r *rpc.Response) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobClientCodec) ReadResponseBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobClientCodec) Close() error {
+	return c.rwc.Close()
+}
+
+var errMessageTooLarge = errors.New("cine: message too large")
+
+// limitedConn fails reads once more than limit bytes were read since the last
+// reset. Codecs read ahead, so the limit is approximate by the size of their
+// read buffer.
+type limitedConn struct {
+	io.ReadWriteCloser
+	limit     int64
+	remaining int64
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		return 0, errMessageTooLarge
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.ReadWriteCloser.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// serverCodec bounds the size of requests and the time to read a request and
+// to write a response on connections from other directors. Connections waiting
+// for the next request stay open; they are owned by the director that dialed
+// them.
+type serverCodec struct {
+	rpc.ServerCodec
+	conn         net.Conn
+	limited      *limitedConn
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if c.limited != nil {
+		c.limited.remaining = c.limited.limit
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	return nil
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	return c.ServerCodec.WriteResponse(r, body)
+}
+
+const kConnected = "200 Connected to Go RPC"
+
+// rpcHandler serves rpc on HTTP CONNECT requests, like net/rpc does, with the
+// codec of the director. Every connection gets its own DirectorApi knowing the
+// node on the other end.
+type rpcHandler struct {
+	director *Director
+}
+
+func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	d := h.director
+	if req.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+	node, verified, err := peerNode(req)
+	if err != nil {
+		d.logger.Warnln("Rejecting connection from", req.RemoteAddr, ":", err)
+		http.Error(w, "403 "+err.Error(), http.StatusForbidden)
+		return
+	}
+	cookies := d.config.cookies
+	clientChallenge := req.Header.Get(kChallengeHeader)
+	if cookies != nil && clientChallenge == "" {
+		d.logger.Warnln("Rejecting connection from", node, req.RemoteAddr, ": no cookie")
+		http.Error(w, "403 cookie required", http.StatusForbidden)
+		return
+	}
+	conn, bufrw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		d.logger.Errorln("rpc hijacking", req.RemoteAddr, ":", err)
+		return
+	}
+	response := "HTTP/1.0 " + kConnected + "\n"
+	var serverChallenge string
+	if cookies != nil {
+		serverChallenge = randomToken()
+		response += kChallengeHeader + ": " + serverChallenge + "\n"
+		response += kProofHeader + ": " + cookies.proof("server", d.nodeName, clientChallenge, serverChallenge) + "\n"
+	}
+	io.WriteString(conn, response+"\n")
+	if cookies != nil && !cookies.acceptCookie(conn, bufrw.Reader, node, clientChallenge, serverChallenge) {
+		d.logger.Warnln("Rejecting connection from", node, req.RemoteAddr, ":", ErrCookieMismatch)
+		conn.Close()
+		return
+	}
+
+	// The cookie proof covers the node name
+	api := &DirectorApi{director: d, addr: nodeHost(req.RemoteAddr)}
+	if verified || cookies != nil {
+		api.peer = node
+	}
+	server := rpc.NewServer()
+	server.Register(api)
+	codec := &serverCodec{
+		conn:         conn,
+		readTimeout:  d.config.readTimeout,
+		writeTimeout: d.config.writeTimeout,
+	}
+	if maxMessageSize := d.config.maxMessageSize; maxMessageSize > 0 {
+		codec.limited = &limitedConn{conn, maxMessageSize, maxMessageSize}
+		codec.ServerCodec = d.config.codec.NewServerCodec(codec.limited)
+	} else {
+		codec.ServerCodec = d.config.codec.NewServerCodec(conn)
+	}
+	server.ServeCodec(codec)
+}
+
+// dial connects to the director at node.
+func (d *Director) dial(node string) (*rpc.Client, error) {
+	var conn net.Conn
+	var err error
+	if dialer, ok := d.config.transport.(nodeDialer); ok {
+		conn, err = dialer.dialFrom(d.nodeName, node, d.config.dialTimeout)
+	} else {
+		conn, err = d.config.transport.Dial(node, d.config.dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.config.tlsClient != nil {
+		tlsConn, err := d.clientTLS(conn, node)
+		if err != nil {
+			conn.Close()
+			return nil, &net.OpError{Op: "dial-tls", Net: node, Err: err}
+		}
+		conn = tlsConn
+	}
+	if d.config.dialTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.config.dialTimeout))
+	}
+	header := kNodeHeader + ": " + d.nodeName + "\n"
+	cookies := d.config.cookies
+	var clientChallenge string
+	if cookies != nil {
+		clientChallenge = randomToken()
+		header += kChallengeHeader + ": " + clientChallenge + "\n"
+	}
+	io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n"+header+"\n")
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, &http.Request{Method: "CONNECT"})
+	if err == nil && resp.StatusCode == http.StatusForbidden {
+		d.logger.Warnln("Connection to", node, "rejected:", resp.Status)
+		conn.Close()
+		return nil, ErrConnectionRejected
+	}
+	if err == nil && resp.Status != kConnected {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, &net.OpError{Op: "dial-http", Net: node, Err: err}
+	}
+	if cookies != nil {
+		err := cookies.proveCookie(conn, r, d.nodeName, node, clientChallenge,
+			resp.Header.Get(kChallengeHeader), resp.Header.Get(kProofHeader))
+		if err != nil {
+			d.logger.Warnln("Cookie handshake with", node, "failed:", err)
+			conn.Close()
+			return nil, err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return rpc.NewClientWithCodec(d.config.codec.NewClientCodec(conn)), nil
+}
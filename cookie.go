@@ -0,0 +1,95 @@
+package cine
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+const (
+	kChallengeHeader = "Cine-Challenge"
+	kProofHeader     = "Cine-Proof"
+	kCookieAccepted  = "OK"
+)
+
+// cookies holds the shared secret of a cluster. Proofs are made with the
+// first secret and verified with any of them.
+type cookies struct {
+	secrets []string
+}
+
+// WithCookie makes directors prove to each other that they know a shared
+// secret before any remote call is accepted, like the Erlang cookie. The
+// secret itself never goes over the wire.
+//
+// To rotate the secret without downtime, first make every director accept
+// the new secret (WithCookie(old, new)), then make them use it
+// (WithCookie(new, old)) and finally drop the old one (WithCookie(new)).
+func WithCookie(secret string, accepted ...string) Option {
+	return func(d *Director) {
+		d.config.cookies = &cookies{append([]string{secret}, accepted...)}
+	}
+}
+
+// proofWith computes the proof of a side of the handshake. Both challenges
+// and the role are part of it so a proof cannot be replayed or reflected.
+func proofWith(secret string, role string, node string, challenges ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, role)
+	io.WriteString(mac, "\x00"+node)
+	for _, challenge := range challenges {
+		io.WriteString(mac, "\x00"+challenge)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *cookies) proof(role string, node string, challenges ...string) string {
+	return proofWith(c.secrets[0], role, node, challenges...)
+}
+
+func (c *cookies) verify(proof string, role string, node string, challenges ...string) bool {
+	for _, secret := range c.secrets {
+		expected := proofWith(secret, role, node, challenges...)
+		if hmac.Equal([]byte(proof), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptCookie finishes the handshake on the server side once the CONNECT
+// response carrying the server proof was sent: the client answers with its
+// own proof and gets the verdict.
+func (c *cookies) acceptCookie(conn io.Writer, r *bufio.Reader, node string, clientChallenge string, serverChallenge string) bool {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	proof := strings.TrimSpace(line)
+	if !c.verify(proof, "client", node, serverChallenge, clientChallenge) {
+		io.WriteString(conn, ErrCookieMismatch.Error()+"\n")
+		return false
+	}
+	io.WriteString(conn, kCookieAccepted+"\n")
+	return true
+}
+
+// proveCookie runs the client side of the handshake after the CONNECT
+// response was received.
+func (c *cookies) proveCookie(conn io.Writer, r *bufio.Reader, self string, server string, clientChallenge string, serverChallenge string, serverProof string) error {
+	if serverChallenge == "" || !c.verify(serverProof, "server", server, clientChallenge, serverChallenge) {
+		return ErrCookieMismatch
+	}
+	io.WriteString(conn, c.proof("client", self, serverChallenge, clientChallenge)+"\n")
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(line) != kCookieAccepted {
+		return ErrCookieMismatch
+	}
+	return nil
+}
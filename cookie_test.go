@@ -0,0 +1,62 @@
+package cine
+
+import (
+	"testing"
+)
+
+func TestCookie(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithCookie("secret"))
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network), WithCookie("secret"))
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	wrong := mustNewDirector(t, "wrong:1", WithTransport(network), WithCookie("guess"))
+	if _, err := wrong.Call(pid, (*Counter).Incr); err != ErrCookieMismatch {
+		t.Errorf("Expected ErrCookieMismatch but got %v\n", err)
+	}
+
+	none := mustNewDirector(t, "none:1", WithTransport(network))
+	if _, err := none.Call(pid, (*Counter).Incr); err != ErrConnectionRejected {
+		t.Errorf("Expected ErrConnectionRejected but got %v\n", err)
+	}
+
+	// A director with a cookie does not talk to one without
+	plainD := mustNewDirector(t, "plain:1", WithTransport(network))
+	plainPid := plainD.StartActor(&Counter{})
+	defer plainD.Stop(plainPid)
+	if _, err := d.Call(plainPid, (*Counter).Incr); err != ErrCookieMismatch {
+		t.Errorf("Expected ErrCookieMismatch but got %v\n", err)
+	}
+}
+
+func TestCookieRotation(t *testing.T) {
+	network := NewMemoryNetwork()
+	rotated := mustNewDirector(t, "rotated:1", WithTransport(network), WithCookie("new", "old"))
+	rotatedPid := rotated.StartActor(&Counter{})
+	defer rotated.Stop(rotatedPid)
+
+	accepting := mustNewDirector(t, "accepting:1", WithTransport(network), WithCookie("old", "new"))
+	acceptingPid := accepting.StartActor(&Counter{})
+	defer accepting.Stop(acceptingPid)
+
+	if _, err := rotated.Call(acceptingPid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if _, err := accepting.Call(rotatedPid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	// Once the old secret is dropped, laggards are rejected
+	done := mustNewDirector(t, "done:1", WithTransport(network), WithCookie("new"))
+	if _, err := done.Call(rotatedPid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if _, err := done.Call(acceptingPid, (*Counter).Incr); err != ErrCookieMismatch {
+		t.Errorf("Expected ErrCookieMismatch but got %v\n", err)
+	}
+}
@@ -0,0 +1,290 @@
+package cine
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kDebugPath       = "/debug/cine/"
+	kMaxCrashReports = 20
+)
+
+// CrashReport describes an actor that panicked.
+type CrashReport struct {
+	Pid    Pid
+	Type   string
+	Method string
+	Time   time.Time
+	Reason string
+	Stack  string
+}
+
+// crashReports keeps the latest crash reports of a director.
+type crashReports struct {
+	lock    sync.Mutex
+	reports []CrashReport
+}
+
+func (d *Director) recordCrash(report CrashReport) {
+	d.crashes.lock.Lock()
+	defer d.crashes.lock.Unlock()
+	d.crashes.reports = append(d.crashes.reports, report)
+	if len(d.crashes.reports) > kMaxCrashReports {
+		d.crashes.reports = d.crashes.reports[len(d.crashes.reports)-kMaxCrashReports:]
+	}
+}
+
+// Crashes returns the latest crash reports of the local actors, the most
+// recent last.
+func (d *Director) Crashes() []CrashReport {
+	d.crashes.lock.Lock()
+	defer d.crashes.lock.Unlock()
+	return append([]CrashReport(nil), d.crashes.reports...)
+}
+
+// NodeInfo describes a node known to the director, as a cluster member or as a
+// monitored node.
+type NodeInfo struct {
+	Name      string
+	Status    string
+	Monitored bool
+	Suspicion float64
+}
+
+// nodes returns the cluster members and the monitored nodes.
+func (d *Director) nodes() []NodeInfo {
+	nodes := make(map[string]*NodeInfo)
+	for _, member := range d.Members() {
+		nodes[member.NodeName] = &NodeInfo{Name: member.NodeName, Status: member.Status.String()}
+	}
+	nm := &d.nodeMonitor
+	now := d.clock.Now()
+	nm.lock.Lock()
+	for name, n := range nm.nodes {
+		info, ok := nodes[name]
+		if !ok {
+			info = &NodeInfo{Name: name}
+			nodes[name] = info
+		}
+		info.Monitored = true
+		// JSON has no infinity
+		info.Suspicion = math.Min(n.detector.Phi(now), math.MaxFloat64)
+		if info.Status == "" {
+			switch n.state {
+			case nodeIsUp:
+				info.Status = "up"
+			case nodeIsDown:
+				info.Status = "down"
+			default:
+				info.Status = "unknown"
+			}
+		}
+	}
+	nm.lock.Unlock()
+
+	infos := make([]NodeInfo, 0, len(nodes))
+	for _, info := range nodes {
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// WithDebug serves a debug view of the director at /debug/cine/ to clients
+// sending the header "Authorization: Bearer <token>". It shows the nodes,
+// actors and recent crashes as JSON, or as HTML with ?format=html. The state
+// of an actor can be inspected and actors can be stopped, suspended and
+// resumed:
+//
+//	curl -H "Authorization: Bearer $TOKEN" http://10.0.0.1:9000/debug/cine/
+//	curl -H "Authorization: Bearer $TOKEN" http://10.0.0.1:9000/debug/cine/actors/12/state
+//	curl -H "Authorization: Bearer $TOKEN" -X POST http://10.0.0.1:9000/debug/cine/actors/12/stop
+//
+// The token must not be empty. Use TLS to keep it secret on the wire.
+func WithDebug(token string) Option {
+	if token == "" {
+		panic("WithDebug requires a token")
+	}
+	return func(d *Director) {
+		d.config.debugToken = token
+	}
+}
+
+type debugHandler struct {
+	director *Director
+	token    string
+}
+
+// debugOverview is the content of the debug index.
+type debugOverview struct {
+	Node    string
+	Nodes   []NodeInfo
+	Actors  []ActorInfo
+	Crashes []CrashReport
+}
+
+func (h *debugHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	auth := req.Header.Get("Authorization")
+	expected := "Bearer " + h.token
+	if subtle.ConstantTimeCompare([]byte(auth), []byte(expected)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cine"`)
+		http.Error(w, "401 unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	d := h.director
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, kDebugPath), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "":
+		h.write(w, req, debugOverview{
+			Node:    d.nodeName,
+			Nodes:   d.nodes(),
+			Actors:  redactActors(d.Actors()),
+			Crashes: redactCrashes(d.Crashes()),
+		})
+	case path == "nodes":
+		h.write(w, req, d.nodes())
+	case path == "actors":
+		h.write(w, req, redactActors(d.Actors()))
+	case path == "crashes":
+		h.write(w, req, redactCrashes(d.Crashes()))
+	case parts[0] == "actors" && len(parts) <= 3:
+		info, ok := h.findActor(parts[1])
+		if !ok {
+			http.Error(w, "404 actor not found", http.StatusNotFound)
+			return
+		}
+		if len(parts) == 2 {
+			h.write(w, req, redactActors([]ActorInfo{info})[0])
+			return
+		}
+		if parts[2] == "state" {
+			state, err := d.GetState(info.Pid)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			h.write(w, req, state)
+			return
+		}
+		actions := map[string]func(Pid) *DirectorError{
+			"stop":    d.Stop,
+			"suspend": d.Suspend,
+			"resume":  d.Resume,
+		}
+		action, ok := actions[parts[2]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		if req.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		d.logger.Warnln("Debug endpoint:", parts[2], info.Pid, "requested by", req.RemoteAddr)
+		if err := action(info.Pid); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.write(w, req, map[string]string{parts[2]: info.Pid.String()})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// findActor finds a local actor by actor id. Capability tokens are not
+// needed, the debug endpoint is authenticated by itself.
+func (h *debugHandler) findActor(id string) (ActorInfo, bool) {
+	actorId, err := strconv.Atoi(id)
+	if err != nil {
+		return ActorInfo{}, false
+	}
+	for _, info := range h.director.Actors() {
+		if info.Pid.ActorId == actorId {
+			return info, true
+		}
+	}
+	return ActorInfo{}, false
+}
+
+// debugPage is rendered by debugTemplate. Raw holds the JSON of responses
+// without tables, like actor states.
+type debugPage struct {
+	debugOverview
+	Raw string
+}
+
+func (h *debugHandler) write(w http.ResponseWriter, req *http.Request, v interface{}) {
+	if req.URL.Query().Get("format") != "html" {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(v)
+		return
+	}
+
+	var page debugPage
+	switch v := v.(type) {
+	case debugOverview:
+		page.debugOverview = v
+	case []NodeInfo:
+		page.Nodes = v
+	case []ActorInfo:
+		page.Actors = v
+	case ActorInfo:
+		page.Actors = []ActorInfo{v}
+	case []CrashReport:
+		page.Crashes = v
+	default:
+		raw, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Raw = string(raw)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugTemplate.Execute(w, page); err != nil {
+		h.director.logger.Errorln("Debug page failed:", err)
+	}
+}
+
+func redactCrashes(reports []CrashReport) []CrashReport {
+	for i := range reports {
+		reports[i].Pid.Token = ""
+	}
+	return reports
+}
+
+// debugTemplate renders a debugPage as tables.
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html><head><title>cine</title>
+<style>table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:2px 6px;vertical-align:top}pre{margin:0}</style>
+</head><body>
+{{with .Node}}<h1>{{.}}</h1>{{end}}
+{{with .Nodes}}<h2>Nodes</h2>
+<table><tr><th>Node</th><th>Status</th><th>Monitored</th><th>Suspicion</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Monitored}}</td><td>{{printf "%.2f" .Suspicion}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Actors}}<h2>Actors</h2>
+<table><tr><th>Pid</th><th>Type</th><th>Name</th><th>Mailbox</th><th>Suspended</th><th>Processed</th><th>Method</th><th>Uptime</th><th>Links</th><th>Monitors</th></tr>
+{{range .}}<tr><td>{{.Pid}}</td><td>{{.Type}}</td><td>{{.Name}}</td><td>{{.Mailbox}}</td><td>{{.Suspended}}</td><td>{{.Processed}}</td><td>{{.Method}}</td><td>{{.Uptime}}</td><td>{{range .Links}}{{.}} {{end}}</td><td>{{range .Monitors}}{{.}} {{end}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Crashes}}<h2>Crashes</h2>
+<table><tr><th>Time</th><th>Pid</th><th>Type</th><th>Method</th><th>Reason</th></tr>
+{{range .}}<tr><td>{{.Time}}</td><td>{{.Pid}}</td><td>{{.Type}}</td><td>{{.Method}}</td><td>{{.Reason}}<pre>{{.Stack}}</pre></td></tr>
+{{end}}</table>{{end}}
+{{with .Raw}}<pre>{{.}}</pre>{{end}}
+</body></html>
+`))
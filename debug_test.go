@@ -0,0 +1,131 @@
+package cine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func debugRequest(t *testing.T, d *Director, method string, path string, token string) (int, string) {
+	req, err := http.NewRequest(method, "http://"+d.NodeName()+kDebugPath+path, nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestDebugAuthentication(t *testing.T) {
+	d := mustNewDirector(t, "127.0.0.1:0", WithDebug("secret"))
+	if code, _ := debugRequest(t, d, "GET", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token but got %v\n", code)
+	}
+	if code, _ := debugRequest(t, d, "GET", "", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token but got %v\n", code)
+	}
+	if code, _ := debugRequest(t, d, "GET", "", "secret"); code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v\n", code)
+	}
+
+	// Not mounted without WithDebug
+	other := newTestDirector(t)
+	if code, _ := debugRequest(t, other, "GET", "", "secret"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v\n", code)
+	}
+}
+
+func TestDebugActors(t *testing.T) {
+	d := mustNewDirector(t, "127.0.0.1:0", WithDebug("secret"), WithCapabilities())
+	pid := d.StartActor(&Worker{Actor{}, "debugged", nil})
+
+	code, body := debugRequest(t, d, "GET", "actors", "secret")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v\n", code)
+	}
+	var infos []ActorInfo
+	if err := json.Unmarshal([]byte(body), &infos); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if len(infos) != 1 || infos[0].Pid.ActorId != pid.ActorId || infos[0].Type != "*cine.Worker" {
+		t.Errorf("Expected the worker but got %v\n", infos)
+	}
+	if strings.Contains(body, pid.Token) {
+		t.Errorf("Expected the capability token to be redacted but got %v\n", body)
+	}
+
+	path := fmt.Sprint("actors/", pid.ActorId)
+	if code, _ := debugRequest(t, d, "GET", path, "secret"); code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v\n", code)
+	}
+	if code, body := debugRequest(t, d, "GET", path+"/state", "secret"); code != http.StatusOK || !strings.Contains(body, "debugged") {
+		t.Errorf("Expected the state of the worker but got %v %v\n", code, body)
+	}
+	if code, _ := debugRequest(t, d, "POST", path+"/suspend", "secret"); code != http.StatusOK || !d.Actors()[0].Suspended {
+		t.Errorf("Expected the worker to be suspended but got %v\n", code)
+	}
+	if code, _ := debugRequest(t, d, "POST", path+"/resume", "secret"); code != http.StatusOK || d.Actors()[0].Suspended {
+		t.Errorf("Expected the worker to be resumed but got %v\n", code)
+	}
+	if code, _ := debugRequest(t, d, "GET", path+"/stop", "secret"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 but got %v\n", code)
+	}
+	if code, _ := debugRequest(t, d, "POST", path+"/stop", "secret"); code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v\n", code)
+	}
+	waitFor(t, "stop", func() bool {
+		return len(d.Actors()) == 0
+	})
+	if code, _ := debugRequest(t, d, "GET", path, "secret"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v\n", code)
+	}
+}
+
+func TestDebugCrashes(t *testing.T) {
+	d := mustNewDirector(t, "127.0.0.1:0", WithDebug("secret"))
+	pid := d.StartActor(&Worker{Actor{}, "crasher", nil})
+	if _, err := d.Call(pid, (*Worker).Crash); err != ErrActorDied {
+		t.Errorf("Expected ErrActorDied but got %v\n", err)
+	}
+
+	crashes := d.Crashes()
+	if len(crashes) != 1 {
+		t.Fatalf("Expected one crash but got %v\n", crashes)
+	}
+	if crashes[0].Pid != pid || crashes[0].Method != "Crash" {
+		t.Errorf("Expected a crash of %v in Crash but got %v\n", pid, crashes[0])
+	}
+	if !strings.Contains(crashes[0].Stack, "Crash") {
+		t.Errorf("Expected the stack trace but got %v\n", crashes[0].Stack)
+	}
+
+	code, body := debugRequest(t, d, "GET", "crashes", "secret")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v\n", code)
+	}
+	var reports []CrashReport
+	if err := json.Unmarshal([]byte(body), &reports); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if len(reports) != 1 || reports[0].Reason != crashes[0].Reason {
+		t.Errorf("Expected the crash report but got %v\n", body)
+	}
+
+	code, body = debugRequest(t, d, "GET", "?format=html", "secret")
+	if code != http.StatusOK || !strings.Contains(body, "<h2>Crashes</h2>") {
+		t.Errorf("Expected an HTML page with the crashes but got %v %v\n", code, body)
+	}
+}
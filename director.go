@@ -1,17 +1,19 @@
 package cine
 
 import (
+	"crypto/tls"
 	"encoding/gob"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/rpc"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/net/context"
-
-	log "github.com/Sirupsen/logrus"
 )
 
 var DefaultDirector *Director
@@ -19,32 +21,26 @@ var initOnce sync.Once
 
 func init() {
 	gob.Register(Pid{})
+	gob.Register(map[string]string{})
 }
 
-func Init(nodeName string) {
+func Init(nodeName string, opts ...Option) error {
+	var err error
 	initOnce.Do(func() {
-		DefaultDirector = NewDirector(nodeName)
+		DefaultDirector, err = NewDirector(nodeName, opts...)
 	})
+	return err
 }
 
-// Ask the kernel for a free open port that is ready to use
-func getPort() int {
-	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
-	if err != nil {
-		panic(err)
-	}
-
-	l, err := net.ListenTCP("tcp", addr)
-	if err != nil {
-		panic(err)
-	}
-	defer l.Close()
-	return l.Addr().(*net.TCPAddr).Port
-}
-
+// InitForTest initializes DefaultDirector on an ephemeral port of the loopback
+// interface.
 func InitForTest() {
 	if DefaultDirector == nil {
-		DefaultDirector = NewDirector(fmt.Sprintf("127.0.0.1:%d", getPort()))
+		d, err := NewDirector("127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		DefaultDirector = d
 	}
 }
 
@@ -92,12 +88,77 @@ func (e *DirectorError) Error() string {
 }
 
 var (
-	ErrActorDied      = &DirectorError{"Actor died"}
-	ErrActorNotFound  = &DirectorError{"Actor not found"}
-	ErrMethodNotFound = &DirectorError{"Method not found"}
-	ErrActorStop      = &DirectorError{"Actor stop"}
+	ErrActorDied          = &DirectorError{"Actor died"}
+	ErrActorNotFound      = &DirectorError{"Actor not found"}
+	ErrMethodNotFound     = &DirectorError{"Method not found"}
+	ErrActorStop          = &DirectorError{"Actor stop"}
+	ErrActorIdle          = &DirectorError{"Actor idle"}
+	ErrKindNotFound       = &DirectorError{"Kind not found"}
+	ErrFactoryNotFound    = &DirectorError{"Factory not found"}
+	ErrLinkedActorDied    = &DirectorError{"Linked actor died"}
+	ErrJoinFailed         = &DirectorError{"Join failed"}
+	ErrNodeUnreachable    = &DirectorError{"Node unreachable"}
+	ErrShutdown           = &DirectorError{"Director shutdown"}
+	ErrShutdownTimeout    = &DirectorError{"Director shutdown timeout"}
+	ErrCallTimeout        = &DirectorError{"Call timeout"}
+	ErrCookieMismatch     = &DirectorError{"Cookie mismatch"}
+	ErrConnectionRejected = &DirectorError{"Connection rejected"}
+	ErrPermissionDenied   = &DirectorError{"Permission denied"}
+	ErrStalePid           = &DirectorError{"Stale pid"}
+	ErrTooManyArgs        = &DirectorError{"Too many arguments"}
+	ErrArgsTooDeep        = &DirectorError{"Arguments nested too deep"}
+	ErrInvalidArgs        = &DirectorError{"Invalid arguments"}
+	ErrTooManyRequests    = &DirectorError{"Too many requests"}
+	ErrMailboxFull        = &DirectorError{"Mailbox full"}
 )
 
+// knownErrors lists the errors that keep their identity when returned by a
+// remote director.
+var knownErrors = []*DirectorError{
+	ErrActorDied,
+	ErrActorNotFound,
+	ErrMethodNotFound,
+	ErrActorStop,
+	ErrActorIdle,
+	ErrKindNotFound,
+	ErrFactoryNotFound,
+	ErrLinkedActorDied,
+	ErrJoinFailed,
+	ErrNodeUnreachable,
+	ErrShutdown,
+	ErrShutdownTimeout,
+	ErrCallTimeout,
+	ErrCookieMismatch,
+	ErrConnectionRejected,
+	ErrPermissionDenied,
+	ErrStalePid,
+	ErrTooManyArgs,
+	ErrArgsTooDeep,
+	ErrInvalidArgs,
+	ErrTooManyRequests,
+	ErrMailboxFull,
+}
+
+// connectError returns err if it is a *DirectorError, like a failed
+// authentication or a stale pid, and fallback otherwise.
+func connectError(err error, fallback *DirectorError) *DirectorError {
+	if derr, ok := err.(*DirectorError); ok {
+		return derr
+	}
+	return fallback
+}
+
+// canonicalError maps an error decoded from a remote response to the matching
+// known error so it can be compared with ==.
+func canonicalError(err *DirectorError) *DirectorError {
+	for _, known := range knownErrors {
+		if err.Message == known.Message {
+			return known
+		}
+	}
+	return err
+}
+
 type PanicError struct {
 	PanicErr interface{}
 }
@@ -115,8 +176,8 @@ type ActorImplementor interface {
 }
 
 type actorLike interface {
-	call(function interface{}, args ...interface{}) ([]interface{}, *DirectorError)
-	cast(done chan *ActorCall, function interface{}, args ...interface{})
+	call(span SpanContext, function interface{}, args ...interface{}) ([]interface{}, *DirectorError)
+	cast(span SpanContext, done chan *ActorCall, function interface{}, args ...interface{})
 	callWithContext(function interface{}, ctx context.Context, args ...interface{}) ([]interface{}, *DirectorError)
 	stop() *DirectorError
 }
@@ -124,96 +185,365 @@ type actorLike interface {
 type Pid struct {
 	NodeName string
 	ActorId  int
+	// Creation is the incarnation of the director that started the actor, so
+	// pids from before a restart of the node are not mistaken for new actors.
+	Creation int64
+	// Token is the secret part of the pids of directors using
+	// WithCapabilities. It is left out of String so logs do not leak it.
+	Token string
 }
 
 func (p Pid) String() string {
-	return fmt.Sprintf("<%s,%d>", p.NodeName, p.ActorId)
+	return fmt.Sprintf("<%s,%d,%d>", p.NodeName, p.ActorId, p.Creation)
 }
 
-type Director struct {
-	nodeName   string
-	pidLock    sync.RWMutex
-	pidMap     map[Pid]*Actor
-	clientLock sync.Mutex
-	clientMap  map[string]*rpc.Client
-	maxActorId int
-	server     *http.Server
+// ParsePid parses the string form of a pid. The capability token is not part
+// of it.
+func ParsePid(s string) (Pid, error) {
+	if !strings.HasPrefix(s, "<") || !strings.HasSuffix(s, ">") {
+		return Pid{}, fmt.Errorf("invalid pid %q", s)
+	}
+	// Node names may contain commas, so split from the end
+	fields := strings.Split(s[1:len(s)-1], ",")
+	if len(fields) < 3 {
+		return Pid{}, fmt.Errorf("invalid pid %q", s)
+	}
+	n := len(fields)
+	actorId, err := strconv.Atoi(fields[n-2])
+	if err != nil {
+		return Pid{}, fmt.Errorf("invalid actor id in pid %q", s)
+	}
+	creation, err := strconv.ParseInt(fields[n-1], 10, 64)
+	if err != nil {
+		return Pid{}, fmt.Errorf("invalid creation in pid %q", s)
+	}
+	nodeName := strings.Join(fields[:n-2], ",")
+	if nodeName == "" {
+		return Pid{}, fmt.Errorf("invalid node name in pid %q", s)
+	}
+	return Pid{NodeName: nodeName, ActorId: actorId, Creation: creation}, nil
 }
 
-func NewDirector(nodeName string) *Director {
+type Director struct {
+	nodeName        string
+	pidLock         sync.RWMutex
+	pidMap          map[Pid]*Actor
+	shutDown        bool
+	clientLock      sync.Mutex
+	clientMap       map[string]*rpc.Client
+	maxActorId      int
+	creation        int64
+	config          directorConfig
+	server          *http.Server
+	listener        *trackingListener
+	shutdownTimeout time.Duration
+	clock           Clock
+	logger          Logger
+	virtual         virtualActors
+	supervision     supervision
+	factories       factories
+	membership      membership
+	nodeMonitor     nodeMonitor
+	peerRequests    peerRequests
+	metrics         Metrics
+	tracer          Tracer
+	mux             *http.ServeMux
+	crashes         crashReports
+}
+
+// NewDirector creates a director and starts serving remote calls at nodeName.
+// An error is returned if the port cannot be listened on. If the port of
+// nodeName is 0 an ephemeral port is used and NodeName returns the actual
+// address.
+func NewDirector(nodeName string, opts ...Option) (*Director, error) {
 	d := &Director{
-		nodeName:   nodeName,
-		pidMap:     make(map[Pid]*Actor),
-		clientMap:  make(map[string]*rpc.Client),
-		maxActorId: 0,
+		nodeName:        nodeName,
+		pidMap:          make(map[Pid]*Actor),
+		clientMap:       make(map[string]*rpc.Client),
+		maxActorId:      0,
+		creation:        time.Now().UnixNano(),
+		config:          defaultDirectorConfig(),
+		shutdownTimeout: kDefaultShutdownTimeout,
+		clock:           SystemClock,
+		logger:          defaultLogger(),
+		metrics:         noMetrics{},
+		tracer:          noTracer{},
+		virtual: virtualActors{
+			kinds:       make(map[string]Kind),
+			placement:   LocalPlacement{},
+			activations: make(map[Identity]Pid),
+			cache:       make(map[Identity]Pid),
+		},
+		supervision: supervision{
+			monitors: make(map[Pid][]Pid),
+			links:    make(map[Pid][]Pid),
+		},
+		factories: factories{
+			factories: make(map[string]Factory),
+		},
+		membership: membership{
+			members:   make(map[string]Member),
+			monitored: make(map[string]bool),
+			interval:  kDefaultGossipInterval,
+			rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		},
+		nodeMonitor: nodeMonitor{
+			nodes:  make(map[string]*monitoredNode),
+			config: DefaultFailureDetectorConfig,
+		},
+		peerRequests: peerRequests{
+			inflight: make(map[string]int),
+		},
+	}
+	d.nodeMonitor.transport = d
+	for _, opt := range opts {
+		opt(d)
+	}
+	// Options can give the clock after the tracer
+	if t, ok := d.tracer.(clockedTracer); ok {
+		t.setClock(d.clock)
 	}
-	d.startServer()
-	return d
+	if err := d.startServer(); err != nil {
+		return nil, err
+	}
+	return d, nil
 }
 
-func (d *Director) startServer() {
-	rpc := rpc.NewServer()
-	directorApi := &DirectorApi{
-		director: d,
+// SetClock replaces the clock of the director. It should be called before any
+// actor is started. Note that the read/write timeouts of the HTTP server are
+// socket deadlines and always follow the wall clock.
+func (d *Director) SetClock(clock Clock) {
+	d.clock = clock
+	if t, ok := d.tracer.(clockedTracer); ok {
+		t.setClock(clock)
 	}
-	rpc.Register(directorApi)
+}
 
-	_, port, err := net.SplitHostPort(d.nodeName)
-	if err != nil {
-		panic(err)
+// Clock returns the clock of the director.
+func (d *Director) Clock() Clock {
+	return d.clock
+}
+
+// Handle serves handler at pattern on the HTTP server of the director, next to
+// the remote calls. Anything that can connect to the director can reach it.
+func (d *Director) Handle(pattern string, handler http.Handler) {
+	d.mux.Handle(pattern, handler)
+}
+
+// NodeName returns the address of the director advertised to other directors.
+func (d *Director) NodeName() string {
+	return d.nodeName
+}
+
+func (d *Director) startServer() error {
+	l := d.config.listener
+	if l == nil {
+		addr, err := d.listenAddr()
+		if err != nil {
+			return err
+		}
+		l, err = d.config.transport.Listen(addr)
+		if err != nil {
+			return err
+		}
+	}
+	if _, isUnix := unixSocketPath(d.nodeName); !isUnix {
+		host, port, err := net.SplitHostPort(d.nodeName)
+		if err != nil {
+			l.Close()
+			return err
+		}
+		if port == "0" {
+			// Advertise the port picked by the kernel
+			_, port, err = net.SplitHostPort(l.Addr().String())
+			if err != nil {
+				l.Close()
+				return err
+			}
+			d.nodeName = net.JoinHostPort(host, port)
+		}
+	}
+	d.listener = newTrackingListener(l, d.metrics)
+	var served net.Listener = d.listener
+	if d.config.tlsServer != nil {
+		served = tls.NewListener(d.listener, d.config.tlsServer)
 	}
 
+	d.mux = http.NewServeMux()
+	d.mux.Handle(rpc.DefaultRPCPath, &rpcHandler{d})
+	if d.config.debugToken != "" {
+		d.mux.Handle(kDebugPath, &debugHandler{d, d.config.debugToken})
+	}
 	d.server = &http.Server{
-		Addr:           ":" + port,
-		Handler:        rpc,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
-	}
-	var wg sync.WaitGroup
-	wg.Add(1)
+		Handler:        d.mux,
+		ReadTimeout:    d.config.readTimeout,
+		WriteTimeout:   d.config.writeTimeout,
+		MaxHeaderBytes: kDefaultMaxHeaderBytes,
+	}
+	d.logger.Infoln("Director listening at", d.nodeName)
 	go func() {
-		log.Infoln("Director listening at", d.nodeName)
-		// Partially ensure the director is up before returning
-		wg.Done()
-		log.Fatalln(d.server.ListenAndServe())
+		if err := d.server.Serve(served); err != nil && err != http.ErrServerClosed {
+			d.logger.Errorln("Director", d.nodeName, "stopped serving:", err)
+		}
 	}()
-	wg.Wait()
+	return nil
+}
+
+// listenAddr returns the address the director listens on. TCP directors
+// listen on all interfaces unless told otherwise.
+func (d *Director) listenAddr() (string, error) {
+	if d.config.listenAddr != "" {
+		return d.config.listenAddr, nil
+	}
+	if _, ok := d.config.transport.(TCPTransport); !ok {
+		return d.nodeName, nil
+	}
+	if _, ok := unixSocketPath(d.nodeName); ok {
+		return d.nodeName, nil
+	}
+	_, port, err := net.SplitHostPort(d.nodeName)
+	if err != nil {
+		return "", err
+	}
+	return ":" + port, nil
 }
 
 // createPid must be called within d.pidLock critical section
 func (d *Director) createPid() Pid {
 	d.maxActorId += 1
-	return Pid{d.nodeName, d.maxActorId}
+	pid := Pid{NodeName: d.nodeName, ActorId: d.maxActorId, Creation: d.creation}
+	if d.config.capabilities {
+		pid.Token = randomToken()
+	}
+	return pid
 }
 
 func (d *Director) StartActor(actorImpl ActorImplementor) Pid {
 	actor := actorImpl.getActor()
-	actorImpl.startMessageLoop(actorImpl)
 	d.pidLock.Lock()
-	defer d.pidLock.Unlock()
 	pid := d.createPid()
+	d.pidLock.Unlock()
+	// The actor thread may use pid and director (e.g. for the clock) as soon
+	// as it starts
 	actor.pid = pid
 	actor.director = d
+	actor.startedAt = d.clock.Now()
+	actorImpl.startMessageLoop(actorImpl)
+	d.metrics.Add(kMetricActorsStarted, nil, 1)
+
+	d.pidLock.Lock()
+	defer d.pidLock.Unlock()
+	if d.shutDown {
+		// Shutdown already collected the actors to stop
+		d.logger.Warnln("Actor", pid, "started after shutdown, stopping it")
+		actor.stopWithReason(ErrShutdown)
+		return pid
+	}
 	d.pidMap[pid] = actor
 	return pid
 }
 
-func (d *Director) removeClient(pid Pid) {
+// isShutDown returns true once Shutdown was called. shutDown is protected by
+// pidLock so no actor is registered after Shutdown collected them.
+func (d *Director) isShutDown() bool {
+	d.pidLock.RLock()
+	defer d.pidLock.RUnlock()
+	return d.shutDown
+}
+
+// client returns the connection to node, dialing it if there is none yet.
+func (d *Director) client(node string) (*rpc.Client, error) {
+	d.clientLock.Lock()
+	client, ok := d.clientMap[node]
+	d.clientLock.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	client, err := d.dial(node)
+	if err != nil {
+		d.metrics.Add(kMetricDialFailures, Labels{"node": node}, 1)
+		return nil, err
+	}
 	d.clientLock.Lock()
 	defer d.clientLock.Unlock()
+	if cached, ok := d.clientMap[node]; ok {
+		// Dialed concurrently
+		client.Close()
+		return cached, nil
+	}
+	d.clientMap[node] = client
+	return client, nil
+}
+
+// dropClient closes the connection to node unless it was replaced already.
+func (d *Director) dropClient(node string, client *rpc.Client) {
+	d.clientLock.Lock()
+	if d.clientMap[node] == client {
+		delete(d.clientMap, node)
+	}
+	d.clientLock.Unlock()
+	client.Close()
+}
+
+// cachedClient sends messages on the cached connection to a node. A
+// connection that was closed is dialed again; requests are only resent if the
+// closed connection did not send them.
+type cachedClient struct {
+	director *Director
+	node     string
+	client   *rpc.Client
+}
 
-	delete(d.clientMap, pid.NodeName)
+func (c *cachedClient) Go(serviceMethod string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call {
+	if done == nil {
+		done = make(chan *rpc.Call, 1)
+	}
+	call := c.client.Go(serviceMethod, args, reply, done)
+	select {
+	case <-call.Done:
+		if call.Error != rpc.ErrShutdown {
+			call.Done <- call
+			return call
+		}
+	default:
+		return call
+	}
+
+	// Closed before sending, e.g. the other director restarted
+	c.director.dropClient(c.node, c.client)
+	client, err := c.director.client(c.node)
+	if err != nil {
+		c.director.logger.Debugln("Cannot connect to", c.node, err)
+		call.Done <- call
+		return call
+	}
+	return client.Go(serviceMethod, args, reply, done)
+}
+
+func (c *cachedClient) Close() error {
+	c.director.dropClient(c.node, c.client)
+	return nil
 }
 
 func (d *Director) removeActor(pid Pid) {
 	d.pidLock.Lock()
-	defer d.pidLock.Unlock()
-
+	actor, ok := d.pidMap[pid]
 	delete(d.pidMap, pid)
+	d.pidLock.Unlock()
+
+	if ok && actor.identity.Kind != "" {
+		d.deactivated(actor.identity, pid)
+	}
 }
 
+// localActorFromPid finds a local actor. The whole pid must match, including
+// the capability token. Pids of a previous incarnation of the director give
+// ErrStalePid.
 func (d *Director) localActorFromPid(pid Pid) (*Actor, error) {
+	if pid.Creation != d.creation {
+		return nil, ErrStalePid
+	}
 	d.pidLock.RLock()
 	defer d.pidLock.RUnlock()
 
@@ -225,32 +555,37 @@ func (d *Director) localActorFromPid(pid Pid) (*Actor, error) {
 }
 
 func (d *Director) remoteActorFromPid(pid Pid) (*RemoteActor, error) {
-	d.clientLock.Lock()
-	client, ok := d.clientMap[pid.NodeName]
-	d.clientLock.Unlock()
-	// It's okay that we override client due to race condition
-	if !ok {
-		var err error
-		client, err = rpc.DialHTTP("tcp", pid.NodeName)
-		if err != nil {
-			return nil, err
-		}
-		// TODO(serialx): We don't cache the client connections for now.
-		// The reason is that client connection closes after the ReadTimeout/
-		// WriteTimeout	which is currently 10 seconds. We need to manage that
-		// before we can reliably cache the client connections.
-		//d.clientLock.Lock()
-		//d.clientMap[pid.NodeName] = client
-		//d.clientLock.Unlock()
+	client, err := d.client(pid.NodeName)
+	if err != nil {
+		return nil, err
 	}
 	rActor := &RemoteActor{
 		pid:      pid,
-		client:   client,
+		client:   d.wrapClient(pid.NodeName, &cachedClient{d, pid.NodeName, client}),
 		director: d,
 	}
 	return rActor, nil
 }
 
+// wrapClient lets the transport intercept the messages sent to node.
+func (d *Director) wrapClient(node string, client rpcClient) rpcClient {
+	if w, ok := d.config.transport.(clientWrapper); ok {
+		return w.wrapClient(d.nodeName, node, client, d.clock)
+	}
+	return client
+}
+
+// remoteCall calls a DirectorApi method on node and waits for the reply.
+func (d *Director) remoteCall(node string, method string, req interface{}, reply interface{}) *DirectorError {
+	rActor, err := d.remoteActorFromPid(Pid{NodeName: node})
+	if err != nil {
+		d.logger.Debugln("Cannot connect to", node, err)
+		return connectError(err, ErrNodeUnreachable)
+	}
+	call := rActor.client.Go("DirectorApi."+method, req, reply, nil)
+	return rActor.handleCall(call)
+}
+
 func (d *Director) actorFromPid(pid Pid) (actorLike, error) {
 	if pid.NodeName != d.nodeName {
 		return d.remoteActorFromPid(pid)
@@ -260,56 +595,104 @@ func (d *Director) actorFromPid(pid Pid) (actorLike, error) {
 
 // Call method calls the function on the pid actors goroutine.
 // ErrActorNotFound can be returned if pid does not exist or remote node is
-// unavailable.
+// unavailable. The call starts a new trace; handlers continue their own trace
+// with CallWithContext and the context they were given.
 func (d *Director) Call(pid Pid, function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
 	actor, err := d.actorFromPid(pid)
 	if err != nil {
-		return nil, ErrActorNotFound
+		return nil, connectError(err, ErrActorNotFound)
 	}
-	return actor.call(function, args...)
+	span := d.startSpan("call", SpanContext{}, pid, function)
+	ret, callErr := actor.call(span.Context(), function, args...)
+	endSpan(span, callErr)
+	return ret, callErr
 }
 
+// Cast casts function to pid. Like Call, it starts a new trace.
 func (d *Director) Cast(pid Pid, done chan *ActorCall, function interface{}, args ...interface{}) {
+	d.cast(SpanContext{}, pid, done, function, args...)
+}
+
+// cast casts function to pid within the trace of parent.
+func (d *Director) cast(parent SpanContext, pid Pid, done chan *ActorCall, function interface{}, args ...interface{}) {
 	actor, err := d.actorFromPid(pid)
 	if err != nil {
 		return
 	}
-	actor.cast(done, function, args...)
+	span := d.startSpan("cast", parent, pid, function)
+	actor.cast(span.Context(), done, function, args...)
+	span.End()
 }
 
+// CallWithContext calls function with ctx as first argument. The call is
+// traced as part of the span of ctx, if any.
 func (d *Director) CallWithContext(pid Pid, function interface{}, ctx context.Context, args ...interface{}) ([]interface{}, *DirectorError) {
 	actor, err := d.actorFromPid(pid)
 	if err != nil {
-		return nil, ErrActorNotFound
+		return nil, connectError(err, ErrActorNotFound)
 	}
+	span := d.startSpan("call", SpanFromContext(ctx), pid, function)
+	ret, callErr := d.waitWithContext(actor, function, ContextWithSpan(ctx, span.Context()), args...)
+	endSpan(span, callErr)
+	return ret, callErr
+}
 
+// waitWithContext calls function on actor and gives up when ctx is done.
+func (d *Director) waitWithContext(actor actorLike, function interface{}, ctx context.Context, args ...interface{}) ([]interface{}, *DirectorError) {
 	type Return struct {
 		ret []interface{}
 		err *DirectorError
 	}
+	// The deadline is measured by the director clock as well so a FakeClock
+	// can expire calls deterministically.
+	var expired chan struct{}
+	if dl, ok := ctx.Deadline(); ok {
+		timeout := dl.Sub(d.clock.Now())
+		if timeout <= 0 {
+			return nil, &DirectorError{context.DeadlineExceeded.Error()}
+		}
+		expired = make(chan struct{})
+		t := d.clock.AfterFunc(timeout, func() { close(expired) })
+		defer t.Stop()
+	}
+
 	c := make(chan Return, 1)
 	go func() { ret, err := actor.callWithContext(function, ctx, args...); c <- Return{ret: ret, err: err} }()
 	select {
 	case <-ctx.Done():
 		return nil, &DirectorError{ctx.Err().Error()}
+	case <-expired:
+		return nil, &DirectorError{context.DeadlineExceeded.Error()}
 	case ret := <-c:
 		return ret.ret, ret.err
 	}
-
-	return actor.callWithContext(function, ctx, args...)
 }
 
 func (d *Director) Stop(pid Pid) *DirectorError {
 	actor, err := d.actorFromPid(pid)
 	if err != nil {
-		return ErrActorNotFound
+		return connectError(err, ErrActorNotFound)
 	}
-	actor.stop()
-	return nil
+	return actor.stop()
 }
 
 type DirectorApi struct {
 	director *Director
+	// peer is the node name of the director on the other end of the
+	// connection, verified against its certificate or by the cookie
+	// handshake. It is empty if the name could not be verified.
+	peer string
+	// addr is the address of the other end of the connection
+	addr string
+}
+
+// peerKey identifies the other end of the connection for the limits of
+// requests per peer. Unverified peers are only known by their address.
+func (d *DirectorApi) peerKey() string {
+	if d.peer != "" {
+		return d.peer
+	}
+	return "addr:" + d.addr
 }
 
 type RemoteRequest struct {
@@ -317,6 +700,8 @@ type RemoteRequest struct {
 	FunctionName string
 	Args         []interface{}
 	Timeout      string
+	// Span is the span of the caller
+	Span SpanContext
 }
 
 type RemoteResponse struct {
@@ -324,28 +709,49 @@ type RemoteResponse struct {
 	Return []interface{}
 }
 
-func (d *DirectorApi) findFun(r RemoteRequest) (interface{}, *DirectorError) {
+func (d *DirectorApi) findFun(r RemoteRequest) (*Actor, interface{}, *DirectorError) {
 	actor, err := d.director.localActorFromPid(r.Pid)
 	if err != nil {
-		return nil, ErrActorNotFound
+		return nil, nil, connectError(err, ErrActorNotFound)
 	}
 
+	if !isExposed(actor.receiver.Interface(), r.FunctionName) {
+		return nil, nil, ErrMethodNotFound
+	}
 	t := actor.receiver.Type()
 	method, ok := t.MethodByName(r.FunctionName)
 	if !ok {
-		return nil, ErrMethodNotFound
+		return nil, nil, ErrMethodNotFound
+	}
+	if err := d.authorize(r.Pid, r.FunctionName); err != nil {
+		return nil, nil, err
+	}
+	if err := d.checkArgs(r.Args); err != nil {
+		return nil, nil, err
 	}
 	fun := method.Func.Interface()
-	return fun, nil
+	return actor, fun, nil
 }
 
 func (d *DirectorApi) HandleRemoteCall(r RemoteRequest, reply *RemoteResponse) error {
-	fun, err := d.findFun(r)
+	actor, fun, err := d.findFun(r)
 	if err != nil {
 		reply.Err = err
 		return nil
 	}
-	ret, err := d.director.Call(r.Pid, fun, r.Args...)
+	if !validArgs(actor, fun, r.Args) {
+		reply.Err = ErrInvalidArgs
+		return nil
+	}
+	release, err := d.admit(actor)
+	if err != nil {
+		reply.Err = err
+		return nil
+	}
+	defer release()
+	span := d.director.startSpan("serve", r.Span, r.Pid, fun)
+	ret, err := actor.call(span.Context(), fun, r.Args...)
+	endSpan(span, err)
 	if err != nil {
 		reply.Err = err
 		return nil
@@ -355,7 +761,7 @@ func (d *DirectorApi) HandleRemoteCall(r RemoteRequest, reply *RemoteResponse) e
 }
 
 func (d *DirectorApi) HandleRemoteCallWithContext(r RemoteRequest, reply *RemoteResponse) error {
-	fun, err := d.findFun(r)
+	actor, fun, err := d.findFun(r)
 	if err != nil {
 		reply.Err = err
 		return nil
@@ -366,10 +772,22 @@ func (d *DirectorApi) HandleRemoteCallWithContext(r RemoteRequest, reply *Remote
 		reply.Err = &DirectorError{parseErr.Error()}
 		return nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := ContextWithTimeout(d.director.clock, context.Background(), timeout)
 	defer cancel()
+	if !validArgs(actor, fun, append([]interface{}{ctx}, r.Args...)) {
+		reply.Err = ErrInvalidArgs
+		return nil
+	}
+	release, err := d.admit(actor)
+	if err != nil {
+		reply.Err = err
+		return nil
+	}
+	defer release()
 
-	ret, err := d.director.CallWithContext(r.Pid, fun, ctx, r.Args...)
+	span := d.director.startSpan("serve", r.Span, r.Pid, fun)
+	ret, err := d.director.waitWithContext(actor, fun, ContextWithSpan(ctx, span.Context()), r.Args...)
+	endSpan(span, err)
 	if err != nil {
 		reply.Err = err
 		return nil
@@ -379,17 +797,40 @@ func (d *DirectorApi) HandleRemoteCallWithContext(r RemoteRequest, reply *Remote
 }
 
 func (d *DirectorApi) HandleRemoteCast(r RemoteRequest, reply *RemoteResponse) error {
-	fun, err := d.findFun(r)
+	actor, fun, err := d.findFun(r)
 	if err != nil {
 		reply.Err = err
 		return nil
 	}
+	if !validArgs(actor, fun, r.Args) {
+		reply.Err = ErrInvalidArgs
+		return nil
+	}
+	release, err := d.admit(actor)
+	if err != nil {
+		reply.Err = err
+		return nil
+	}
+	// The request counts against the limits until the actor processed it
 	done := make(chan *ActorCall, 1)
-	d.director.Cast(r.Pid, done, fun, r.Args...)
+	span := d.director.startSpan("serve", r.Span, r.Pid, fun)
+	actor.cast(span.Context(), done, fun, r.Args...)
+	span.End()
+	go func() {
+		select {
+		case <-done:
+		case <-actor.terminated:
+		}
+		release()
+	}()
 	return nil
 }
 
 func (d *DirectorApi) HandleRemoteStop(r RemoteRequest, reply *RemoteResponse) error {
+	if err := d.authorize(r.Pid, kStopMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
 	err := d.director.Stop(r.Pid)
 	if err != nil {
 		reply.Err = err
@@ -1,6 +1,8 @@
 package cine
 
 import (
+	"fmt"
+	"net"
 	"strings"
 	"testing"
 	"time"
@@ -20,7 +22,13 @@ func (b *Phonebook) Sleep(ctx context.Context, dstr string) {
 	if err != nil {
 		panic(err)
 	}
-	time.Sleep(d)
+	woken := make(chan struct{})
+	t := b.clock().AfterFunc(d, func() { close(woken) })
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-woken:
+	}
 }
 
 func (b *Phonebook) Add(name string, number int) {
@@ -35,17 +43,45 @@ func (b *Phonebook) Lookup(name string) (int, bool) {
 	return a, ok
 }
 
+func (b *Phonebook) ExposedMethods() []string {
+	return []string{"Sleep", "Add", "Lookup"}
+}
+
 func (b *Phonebook) Terminate(errReason error) {
 	log.Infoln("Actor terminated:", errReason)
 }
 
+func mustNewDirector(t *testing.T, nodeName string, opts ...Option) *Director {
+	d, err := NewDirector(nodeName, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	return d
+}
+
+func newTestDirector(t *testing.T) *Director {
+	return mustNewDirector(t, "127.0.0.1:0")
+}
+
+// unusedAddr returns an address nobody listens on.
+func unusedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
 func TestDirector(t *testing.T) {
-	Init("127.0.0.1:9000")
+	if err := Init("node:9000", WithTransport(NewMemoryNetwork())); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
 	book := Phonebook{Actor{}, make(map[string]int)}
 	pid := StartActor(&book)
 	defer Stop(pid)
-	if pid.NodeName != "127.0.0.1:9000" {
-		t.Errorf("pid.NodeName shoud be 127.0.0.1:9000 but was %v\n", pid.NodeName)
+	if pid.NodeName != "node:9000" {
+		t.Errorf("pid.NodeName shoud be node:9000 but was %v\n", pid.NodeName)
 	}
 
 	Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 1234)
@@ -64,12 +100,13 @@ func TestDirector(t *testing.T) {
 }
 
 func TestRemoteDirector(t *testing.T) {
-	remoteD := NewDirector("127.0.0.1:9001")
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:9001", WithTransport(network))
 	book := Phonebook{Actor{}, make(map[string]int)}
 	pid := remoteD.StartActor(&book)
 	defer remoteD.Stop(pid)
-	if pid.String() != "<127.0.0.1:9001,1>" {
-		t.Errorf("pid.NodeName shoud be 127.0.0.1:9001 but was %v\n", pid.NodeName)
+	if pid.String() != fmt.Sprintf("<remote:9001,1,%d>", pid.Creation) {
+		t.Errorf("pid.NodeName shoud be remote:9001 but was %v\n", pid.NodeName)
 	}
 
 	remoteD.Call(pid, (*Phonebook).Add, "Jane", 1234)
@@ -81,13 +118,19 @@ func TestRemoteDirector(t *testing.T) {
 		t.Errorf("Expected 1234 return but got %v\n", r)
 	}
 
-	d := NewDirector("127.0.0.1:9002")
+	d := mustNewDirector(t, "local:9002", WithTransport(network))
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2341)
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2342)
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2343)
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2344)
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2345)
 	d.Cast(pid, make(chan *ActorCall, 1), (*Phonebook).Add, "Jane", 2346)
+	// Requests sharing a connection are handled concurrently, the call could
+	// overtake the casts
+	waitFor(t, "panic", func() bool {
+		_, err := remoteD.localActorFromPid(pid)
+		return err != nil
+	})
 
 	r, err = d.Call(pid, (*Phonebook).Lookup, "Jane")
 	if err == nil {
@@ -96,20 +139,37 @@ func TestRemoteDirector(t *testing.T) {
 }
 
 func TestRemoteDirectorWithContext(t *testing.T) {
-	remoteD := NewDirector("127.0.0.1:9003")
+	network := NewMemoryNetwork()
+	clock := NewFakeClock(time.Now())
+	remoteD := mustNewDirector(t, "remote:9003", WithTransport(network))
+	remoteD.SetClock(clock)
 	book := Phonebook{Actor{}, make(map[string]int)}
 	pid := remoteD.StartActor(&book)
 	defer remoteD.Stop(pid)
-	if pid.String() != "<127.0.0.1:9003,1>" {
-		t.Errorf("pid.NodeName shoud be 127.0.0.1:9003 but was %v\n", pid.NodeName)
+	if pid.String() != fmt.Sprintf("<remote:9003,1,%d>", pid.Creation) {
+		t.Errorf("pid.NodeName shoud be remote:9003 but was %v\n", pid.NodeName)
 	}
 
+	d := mustNewDirector(t, "local:9004", WithTransport(network))
+	d.SetClock(clock)
+
 	sleepTime := time.Duration(time.Second * 3)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*1))
+	ctx, cancel := ContextWithTimeout(clock, context.Background(), time.Duration(time.Second*1))
 	defer cancel()
 
-	d := NewDirector("127.0.0.1:9004")
-	_, err := d.CallWithContext(pid, (*Phonebook).Sleep, ctx, sleepTime.String())
+	c := make(chan *DirectorError, 1)
+	go func() {
+		_, err := d.CallWithContext(pid, (*Phonebook).Sleep, ctx, sleepTime.String())
+		c <- err
+	}()
+	// The deadlines of both directors are armed once the actor sleeps
+	waitFor(t, "sleep", func() bool {
+		infos := remoteD.Actors()
+		return len(infos) == 1 && infos[0].Method == "Sleep"
+	})
+	clock.Advance(time.Second)
+
+	err := <-c
 	if err == nil {
 		t.Error("Expected call error, but got no error")
 	}
@@ -117,3 +177,54 @@ func TestRemoteDirectorWithContext(t *testing.T) {
 		t.Errorf("Not expected error!, expected: context..., actual: %s", err.Error())
 	}
 }
+
+func TestParsePid(t *testing.T) {
+	pids := []Pid{
+		{NodeName: "127.0.0.1:9000", ActorId: 1, Creation: 1234},
+		{NodeName: "unix:/tmp/a,b.sock", ActorId: 42, Creation: -1},
+	}
+	for _, pid := range pids {
+		parsed, err := ParsePid(pid.String())
+		if err != nil {
+			t.Errorf("Expected no error but got %v\n", err)
+		}
+		if parsed != pid {
+			t.Errorf("Expected %v but got %v\n", pid, parsed)
+		}
+	}
+	for _, s := range []string{"", "<>", "127.0.0.1:9000,1,2", "<127.0.0.1:9000,1>", "<,1,2>", "<node:1,a,2>", "<node:1,1,b>"} {
+		if _, err := ParsePid(s); err == nil {
+			t.Errorf("Expected %q not to parse\n", s)
+		}
+	}
+}
+
+func TestStalePid(t *testing.T) {
+	network := NewMemoryNetwork()
+	old := mustNewDirector(t, "restarted:1", WithTransport(network))
+	oldPid := old.StartActor(&Counter{})
+	if err := old.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+
+	restarted := mustNewDirector(t, "restarted:1", WithTransport(network))
+	pid := restarted.StartActor(&Counter{})
+	defer restarted.Stop(pid)
+	if pid.ActorId != oldPid.ActorId || pid.Creation == oldPid.Creation {
+		t.Fatalf("Expected a new incarnation of %v but got %v\n", oldPid, pid)
+	}
+
+	if _, err := restarted.Call(oldPid, (*Counter).Incr); err != ErrStalePid {
+		t.Errorf("Expected ErrStalePid but got %v\n", err)
+	}
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	if _, err := d.Call(oldPid, (*Counter).Incr); err != ErrStalePid {
+		t.Errorf("Expected ErrStalePid but got %v\n", err)
+	}
+	if err := d.Stop(oldPid); err != ErrStalePid {
+		t.Errorf("Expected ErrStalePid but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
@@ -28,7 +28,9 @@ func (b *Phonebook) Terminate(errReason error) {
 }
 
 func main() {
-	cine.Init("127.0.0.1:9000")
+	if err := cine.Init("127.0.0.1:9000"); err != nil {
+		panic(err)
+	}
 	phonebook := Phonebook{cine.Actor{}, make(map[string]int)}
 	pid := cine.StartActor(&phonebook)
 	fmt.Println("pid:", pid)
@@ -45,26 +45,38 @@ func (p *Player) HandlePing(sender cine.Pid, count int) {
 		cine.Stop(p.Self())
 		return
 	}
-	time.Sleep(500 * time.Millisecond)
 	p.count += 1
+	// Reply later without blocking the mailbox
+	p.SendAfter(500*time.Millisecond, (*Player).SendPong, sender)
+}
 
-	otherPlayer := PlayerProxy{Pid: sender}
+func (p *Player) SendPong(to cine.Pid) {
+	otherPlayer := PlayerProxy{Pid: to}
 	otherPlayer.Pong(p.Self(), p.count)
 }
 
 func (p *Player) HandlePong(sender cine.Pid, count int) {
 	log.Infoln(sender, "Pong:", count)
-	time.Sleep(500 * time.Millisecond)
 	p.count += 1
 
-	otherPlayer := PlayerProxy{Pid: sender}
-	otherPlayer.Ping(p.Self(), p.count)
-
 	if p.count == 10 {
+		// Timers are cancelled on stop, so send the last ping right away
+		p.SendPing(sender)
 		log.Infoln(sender, "Stopping game")
 		cine.Stop(p.Self())
 		return
 	}
+	p.SendAfter(500*time.Millisecond, (*Player).SendPing, sender)
+}
+
+func (p *Player) SendPing(to cine.Pid) {
+	otherPlayer := PlayerProxy{Pid: to}
+	otherPlayer.Ping(p.Self(), p.count)
+}
+
+// ExposedMethods lists the methods the other player can call
+func (p *Player) ExposedMethods() []string {
+	return []string{"HandleStart", "HandlePing", "HandlePong"}
 }
 
 func (p *Player) Terminate(errReason error) {
@@ -75,16 +87,24 @@ func (p *Player) Terminate(errReason error) {
 func main() {
 	playerNum := os.Args[1]
 	if playerNum == "1" {
-		cine.Init("127.0.0.1:3000")
+		if err := cine.Init("127.0.0.1:3000"); err != nil {
+			log.Fatalln(err)
+		}
 	} else {
-		cine.Init("127.0.0.1:3001")
+		if err := cine.Init("127.0.0.1:3001"); err != nil {
+			log.Fatalln(err)
+		}
 	}
 	player := Player{cine.Actor{}, 0}
 	waitGroup.Add(1)
 	pid := cine.StartActor(&player)
 	log.Infoln("pid:", pid)
 	if playerNum == "2" {
-		to := cine.Pid{"127.0.0.1:3000", 1}
+		// The pid printed by player 1
+		to, err := cine.ParsePid(os.Args[2])
+		if err != nil {
+			log.Fatalln(err)
+		}
 		myPlayer := PlayerProxy{Pid: pid}
 		myPlayer.Start(to)
 	}
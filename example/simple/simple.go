@@ -23,7 +23,9 @@ func (p *Phonebook) Terminate(errReason error) {
 }
 
 func main() {
-	cine.Init("127.0.0.1:8000")
+	if err := cine.Init("127.0.0.1:8000"); err != nil {
+		log.Fatalln(err)
+	}
 	phonebook := Phonebook{cine.Actor{}, make(map[string]int)}
 	pid := cine.StartActor(&phonebook)
 
@@ -0,0 +1,80 @@
+package cine
+
+import "reflect"
+
+// RemoteExposer is implemented by actors whose methods can be called from
+// other nodes. Only the listed methods are callable remotely; any other
+// method, or any method of an actor that does not implement RemoteExposer,
+// fails with ErrMethodNotFound. ExposedMethods is called outside of the actor
+// goroutine, so it must not depend on the actor state.
+//
+// ActorDown is always exposed for actors implementing DownHandler, so remote
+// monitors work, and the methods of the embedded Actor never are.
+type RemoteExposer interface {
+	ExposedMethods() []string
+}
+
+// Authorizer decides whether the director node may call method on the local
+// actor pid. node is the name the other director connected with, verified
+// against its certificate when TLS client certificates are required or by the
+// cookie handshake. Without either, the name cannot be trusted and node is
+// empty. Remote stops and exits of linked actors are authorized with the
+// method name Stop, and monitors and links with Monitor, Demonitor and Link on
+// the local actor. Listing the actors of the director, spawning actors and
+// activating virtual actors are authorized with Actors, Spawn and Activate
+// and a pid without actor id. Gossip is authorized with Gossip, once with the
+// local node and once per entry with the node the entry is about; denied
+// entries are ignored.
+type Authorizer func(node string, pid Pid, method string) bool
+
+const kStopMethod = "Stop"
+
+// WithAuthorizer checks every remote call to an exposed method, cast and
+// stop with authorizer. Denied requests fail with ErrPermissionDenied.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(d *Director) {
+		d.config.authorizer = authorizer
+	}
+}
+
+// internalMethods are the methods every actor has through the embedded Actor
+// and the ActorImplementor interface.
+var internalMethods = func() map[string]bool {
+	methods := map[string]bool{"Terminate": true, "ExposedMethods": true}
+	t := reflect.TypeOf(&Actor{})
+	for i := 0; i < t.NumMethod(); i++ {
+		methods[t.Method(i).Name] = true
+	}
+	return methods
+}()
+
+// isExposed returns true if method of receiver can be called remotely.
+func isExposed(receiver interface{}, method string) bool {
+	if internalMethods[method] {
+		return false
+	}
+	if _, ok := receiver.(DownHandler); ok && method == "ActorDown" {
+		return true
+	}
+	exposer, ok := receiver.(RemoteExposer)
+	if !ok {
+		return false
+	}
+	for _, name := range exposer.ExposedMethods() {
+		if name == method {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks a request of the peer with the authorizer of the
+// director, if any.
+func (d *DirectorApi) authorize(pid Pid, method string) *DirectorError {
+	authorizer := d.director.config.authorizer
+	if authorizer == nil || authorizer(d.peer, pid, method) {
+		return nil
+	}
+	d.director.logger.Warnln("Denied", method, "on", pid, "to", d.peer)
+	return ErrPermissionDenied
+}
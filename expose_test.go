@@ -0,0 +1,278 @@
+package cine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Exposer lists a method of the embedded Actor, which stays internal
+type Exposer struct {
+	Actor
+}
+
+func (e *Exposer) Public() bool {
+	return true
+}
+
+func (e *Exposer) Private() bool {
+	return true
+}
+
+func (e *Exposer) ExposedMethods() []string {
+	return []string{"Public", "Self"}
+}
+
+func (e *Exposer) Terminate(errReason error) {
+}
+
+func TestExposedMethods(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network))
+	pid := remoteD.StartActor(&Exposer{})
+	defer remoteD.Stop(pid)
+	testPid := remoteD.StartActor(&TestActor{})
+	defer remoteD.Stop(testPid)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	if _, err := d.Call(pid, (*Exposer).Public); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*Exposer).Private); err != ErrMethodNotFound {
+		t.Errorf("Expected ErrMethodNotFound but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*Exposer).Self); err != ErrMethodNotFound {
+		t.Errorf("Expected ErrMethodNotFound but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*Exposer).Terminate, nil); err != ErrMethodNotFound {
+		t.Errorf("Expected ErrMethodNotFound but got %v\n", err)
+	}
+	// Actors exposing nothing cannot be called remotely
+	if _, err := d.Call(testPid, (*TestActor).GoAddX, 1); err != ErrMethodNotFound {
+		t.Errorf("Expected ErrMethodNotFound but got %v\n", err)
+	}
+	// Local calls are not restricted
+	if _, err := remoteD.Call(pid, (*Exposer).Private); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	type request struct {
+		node   string
+		pid    Pid
+		method string
+	}
+	var lock sync.Mutex
+	var requests []request
+	network := NewMemoryNetwork()
+	// Node names are only authenticated with a cookie or client certificates
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithCookie("secret"),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			requests = append(requests, request{node, pid, method})
+			return node == "trusted:1"
+		}))
+	pid := remoteD.StartActor(&Exposer{})
+	defer remoteD.Stop(pid)
+
+	trusted := mustNewDirector(t, "trusted:1", WithTransport(network), WithCookie("secret"))
+	if _, err := trusted.Call(pid, (*Exposer).Public); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	other := mustNewDirector(t, "other:1", WithTransport(network), WithCookie("secret"))
+	if _, err := other.Call(pid, (*Exposer).Public); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	if err := other.Stop(pid); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	// Methods that are not exposed are not submitted to the authorizer
+	if _, err := trusted.Call(pid, (*Exposer).Private); err != ErrMethodNotFound {
+		t.Errorf("Expected ErrMethodNotFound but got %v\n", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	expected := []request{
+		{"trusted:1", pid, "Public"},
+		{"other:1", pid, "Public"},
+		{"other:1", pid, "Stop"},
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %v but got %v\n", expected, requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("Expected %v but got %v\n", expected[i], requests[i])
+		}
+	}
+}
+
+func TestAuthorizerUnauthenticatedPeer(t *testing.T) {
+	nodes := make(chan string, 1)
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			nodes <- node
+			return node == "trusted:1"
+		}))
+	pid := remoteD.StartActor(&Exposer{})
+	defer remoteD.Stop(pid)
+
+	// Without a cookie or client certificates the name is not trusted
+	impostor := mustNewDirector(t, "trusted:1", WithTransport(network))
+	if _, err := impostor.Call(pid, (*Exposer).Public); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	if node := <-nodes; node != "" {
+		t.Errorf("Expected an empty node but got %v\n", node)
+	}
+}
+
+func TestAuthorizerSupervisionAndSpawn(t *testing.T) {
+	var lock sync.Mutex
+	allowed := map[string]bool{}
+	allow := func(method string, ok bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		allowed[method] = ok
+	}
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return allowed[method]
+		}))
+	remoteD.RegisterFactory("worker", func(args ...interface{}) ActorImplementor {
+		return &Worker{}
+	})
+	remoteD.RegisterKind(Kind{Name: "counter", Factory: newCounter})
+	reasons := make(chan error, 1)
+	pid := remoteD.StartActor(&Worker{name: "remote", reasons: reasons})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	watcher := d.StartActor(&Watcher{downs: make(chan string, 1)})
+	defer d.Stop(watcher)
+
+	if err := d.Monitor(watcher, pid); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	allow(kMonitorMethod, true)
+	if err := d.Monitor(watcher, pid); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if err := d.Demonitor(watcher, pid); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	if err := d.Link(pid, watcher); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	if _, err := d.SpawnOn("remote:1", "worker"); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	d.SetPlacement(HashPlacement{Nodes: []string{"remote:1"}})
+	if _, err := d.CallIdentity(Identity{"counter", "a"}, (*Counter).Incr); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+
+	// A crashing linked actor cannot stop the remote actor without Stop
+	allow(kLinkMethod, true)
+	worker := d.StartActor(&Worker{name: "local"})
+	if err := d.Link(worker, pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	d.Cast(worker, nil, (*Worker).Crash)
+	select {
+	case reason := <-reasons:
+		t.Errorf("Expected the remote actor to keep running but it stopped with %v\n", reason)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLinkCannotStopWithoutAuthorization(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			return method != kStopMethod
+		}))
+	remoteD.RegisterFactory("worker", func(args ...interface{}) ActorImplementor {
+		return &Worker{}
+	})
+	reasons := make(chan error, 1)
+	victim := remoteD.StartActor(&Worker{name: "victim", reasons: reasons})
+	defer remoteD.Stop(victim)
+	dead := remoteD.StartActor(&Worker{})
+	remoteD.Stop(dead)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	// Linking a dead actor would stop the other end
+	if err := d.Link(dead, victim); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	// The spawned actor would stop the victim when it crashes
+	opts := SpawnOptions{Link: victim}
+	if _, err := d.SpawnOnWithOptions("remote:1", "worker", opts); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+	select {
+	case reason := <-reasons:
+		t.Errorf("Expected the victim to keep running but it stopped with %v\n", reason)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Linking a dead remote actor still stops the local end
+	local := make(chan error, 1)
+	worker := d.StartActor(&Worker{name: "local", reasons: local})
+	if err := d.Link(dead, worker); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	expectReason(t, local, ErrLinkedActorDied)
+}
+
+func TestAuthorizerGossip(t *testing.T) {
+	network := NewMemoryNetwork()
+	// Nodes may only gossip about themselves and other:1 may not gossip
+	seed := mustNewDirector(t, "seed:1", WithTransport(network), WithCookie("secret"),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			return method == kGossipMethod && node != "other:1" &&
+				(pid.NodeName == node || pid.NodeName == "seed:1")
+		}))
+	seed.SetGossipInterval(time.Hour)
+	seed.Join()
+
+	d := mustNewDirector(t, "local:1", WithTransport(network), WithCookie("secret"))
+	d.SetGossipInterval(time.Hour)
+	d.membership.lock.Lock()
+	d.membership.members["victim:1"] = Member{"victim:1", MemberDown, 10}
+	d.membership.lock.Unlock()
+	if err := d.Join("seed:1"); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	statuses := memberStatuses(seed)
+	if _, ok := statuses["victim:1"]; ok {
+		t.Errorf("Expected entries about other nodes to be ignored but got %v\n", statuses)
+	}
+	if _, ok := statuses["local:1"]; !ok {
+		t.Errorf("Expected local:1 to be a member but got %v\n", statuses)
+	}
+
+	// Gossip must come from the verified node
+	var resp GossipResponse
+	req := GossipRequest{From: "seed:1", Members: []Member{{"seed:1", MemberDown, 100}}}
+	if err := d.remoteCall("seed:1", "HandleGossip", req, &resp); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if err := canonicalError(resp.Err); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+
+	other := mustNewDirector(t, "other:1", WithTransport(network), WithCookie("secret"))
+	other.SetGossipInterval(time.Hour)
+	if err := other.Join("seed:1"); err != ErrJoinFailed {
+		t.Errorf("Expected ErrJoinFailed but got %v\n", err)
+	}
+}
@@ -0,0 +1,123 @@
+package cine
+
+import (
+	"math"
+	"time"
+)
+
+// FailureDetectorConfig configures the heartbeat based failure detection of
+// monitored nodes.
+type FailureDetectorConfig struct {
+	// HeartbeatInterval is how often heartbeats are sent
+	HeartbeatInterval time.Duration
+	// Threshold is the phi value above which a node is considered down. A
+	// threshold of 8 means a chance of about 1 in 10^8 that the node is falsely
+	// suspected.
+	Threshold float64
+	// MaxSampleSize is the number of heartbeat intervals kept
+	MaxSampleSize int
+	// MinStdDeviation avoids a too sensitive detector when heartbeats are very
+	// regular
+	MinStdDeviation time.Duration
+	// AcceptableHeartbeatPause is added to the expected interval to tolerate
+	// GC pauses and transient network delays
+	AcceptableHeartbeatPause time.Duration
+}
+
+var DefaultFailureDetectorConfig = FailureDetectorConfig{
+	HeartbeatInterval:        time.Second,
+	Threshold:                8,
+	MaxSampleSize:            200,
+	MinStdDeviation:          100 * time.Millisecond,
+	AcceptableHeartbeatPause: 3 * time.Second,
+}
+
+// withDefaults returns the config with zero or invalid fields replaced by the
+// ones of DefaultFailureDetectorConfig. A zero sample size would break the
+// detector and a zero heartbeat interval would spin the heartbeat loop.
+func (c FailureDetectorConfig) withDefaults() FailureDetectorConfig {
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = DefaultFailureDetectorConfig.HeartbeatInterval
+	}
+	if c.Threshold <= 0 || math.IsNaN(c.Threshold) {
+		c.Threshold = DefaultFailureDetectorConfig.Threshold
+	}
+	if c.MaxSampleSize <= 0 {
+		c.MaxSampleSize = DefaultFailureDetectorConfig.MaxSampleSize
+	}
+	if c.MinStdDeviation <= 0 {
+		c.MinStdDeviation = DefaultFailureDetectorConfig.MinStdDeviation
+	}
+	if c.AcceptableHeartbeatPause <= 0 {
+		c.AcceptableHeartbeatPause = DefaultFailureDetectorConfig.AcceptableHeartbeatPause
+	}
+	return c
+}
+
+// PhiAccrualDetector is the phi accrual failure detector of Hayashibara et al.
+// Instead of a boolean it outputs a suspicion level phi computed from the
+// distribution of past heartbeat intervals. It is not safe for concurrent use.
+type PhiAccrualDetector struct {
+	config    FailureDetectorConfig
+	intervals []float64
+	sum       float64
+	squareSum float64
+	last      time.Time
+}
+
+// NewPhiAccrualDetector returns a detector that received its first heartbeat
+// at now. The interval history is bootstrapped from the heartbeat interval.
+// Invalid fields of config are replaced by the defaults.
+func NewPhiAccrualDetector(config FailureDetectorConfig, now time.Time) *PhiAccrualDetector {
+	config = config.withDefaults()
+	f := &PhiAccrualDetector{config: config, last: now}
+	mean := float64(config.HeartbeatInterval)
+	stdDeviation := mean / 4
+	f.add(mean - stdDeviation)
+	f.add(mean + stdDeviation)
+	return f
+}
+
+// Heartbeat records a heartbeat received at now.
+func (f *PhiAccrualDetector) Heartbeat(now time.Time) {
+	interval := now.Sub(f.last)
+	f.last = now
+	if interval > 0 {
+		f.add(float64(interval))
+	}
+}
+
+func (f *PhiAccrualDetector) add(interval float64) {
+	if len(f.intervals) >= f.config.MaxSampleSize {
+		dropped := f.intervals[0]
+		f.intervals = f.intervals[1:]
+		f.sum -= dropped
+		f.squareSum -= dropped * dropped
+	}
+	f.intervals = append(f.intervals, interval)
+	f.sum += interval
+	f.squareSum += interval * interval
+}
+
+// Phi returns the suspicion level at now.
+func (f *PhiAccrualDetector) Phi(now time.Time) float64 {
+	n := float64(len(f.intervals))
+	mean := f.sum / n
+	variance := f.squareSum/n - mean*mean
+	stdDeviation := math.Max(math.Sqrt(math.Max(variance, 0)), float64(f.config.MinStdDeviation))
+	mean += float64(f.config.AcceptableHeartbeatPause)
+
+	elapsed := float64(now.Sub(f.last))
+	// Logistic approximation of the cumulative normal distribution
+	y := (elapsed - mean) / stdDeviation
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// Available returns true if phi at now is below the threshold.
+func (f *PhiAccrualDetector) Available(now time.Time) bool {
+	return f.Phi(now) < f.config.Threshold
+}
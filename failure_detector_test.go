@@ -0,0 +1,182 @@
+package cine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeHeartbeatMode int
+
+const (
+	heartbeatAnswer fakeHeartbeatMode = iota
+	heartbeatDelay
+	heartbeatDrop
+)
+
+var errHeartbeatLost = &DirectorError{"Heartbeat lost"}
+
+// fakeHeartbeats answers, delays or drops heartbeats without any network.
+type fakeHeartbeats struct {
+	lock    sync.Mutex
+	mode    fakeHeartbeatMode
+	release chan struct{}
+}
+
+func (f *fakeHeartbeats) setMode(mode fakeHeartbeatMode) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.mode == heartbeatDelay {
+		close(f.release)
+	}
+	if mode == heartbeatDelay {
+		f.release = make(chan struct{})
+	}
+	f.mode = mode
+}
+
+func (f *fakeHeartbeats) sendHeartbeat(node string) *DirectorError {
+	f.lock.Lock()
+	mode, release := f.mode, f.release
+	f.lock.Unlock()
+	switch mode {
+	case heartbeatDelay:
+		<-release
+	case heartbeatDrop:
+		return errHeartbeatLost
+	}
+	return nil
+}
+
+func expectNoNodeEvent(t *testing.T, events <-chan NodeEvent) {
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected node event %v\n", event)
+	default:
+	}
+}
+
+func TestPhiAccrualDetector(t *testing.T) {
+	now := time.Now()
+	f := NewPhiAccrualDetector(DefaultFailureDetectorConfig, now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		f.Heartbeat(now)
+	}
+
+	if phi := f.Phi(now.Add(time.Second)); phi > 1 {
+		t.Errorf("Expected low suspicion on time but got %v\n", phi)
+	}
+	prev := 0.0
+	for elapsed := time.Second; elapsed <= 10*time.Second; elapsed += time.Second {
+		phi := f.Phi(now.Add(elapsed))
+		if phi < prev {
+			t.Errorf("Expected phi to grow but got %v after %v\n", phi, prev)
+		}
+		prev = phi
+	}
+	if !f.Available(now.Add(4 * time.Second)) {
+		t.Error("Expected a pause within the acceptable pause to be tolerated")
+	}
+	if f.Available(now.Add(10 * time.Second)) {
+		t.Error("Expected a long silence to be suspected")
+	}
+
+	// Irregular heartbeats make the detector more tolerant
+	last := now
+	jittery := NewPhiAccrualDetector(DefaultFailureDetectorConfig, now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Duration(1+2*(i%2)) * time.Second)
+		jittery.Heartbeat(now)
+	}
+	if jittery.Phi(now.Add(6*time.Second)) >= f.Phi(last.Add(6*time.Second)) {
+		t.Error("Expected irregular heartbeats to lower suspicion")
+	}
+}
+
+func TestFailureDetectorConfigDefaults(t *testing.T) {
+	now := time.Now()
+	f := NewPhiAccrualDetector(FailureDetectorConfig{}, now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		f.Heartbeat(now)
+	}
+	if !f.Available(now.Add(time.Second)) {
+		t.Error("Expected a zero config to behave like the default config")
+	}
+
+	d := newTestDirector(t)
+	d.SetFailureDetector(FailureDetectorConfig{Threshold: 12})
+	expected := DefaultFailureDetectorConfig
+	expected.Threshold = 12
+	if config := d.nodeMonitor.config; config != expected {
+		t.Errorf("Expected %+v but got %+v\n", expected, config)
+	}
+}
+
+func TestMonitorNodeFailureDetector(t *testing.T) {
+	d := newTestDirector(t)
+	clock := NewFakeClock(time.Now())
+	d.SetClock(clock)
+	transport := &fakeHeartbeats{}
+	d.nodeMonitor.transport = transport
+	events, cancel := d.SubscribeNodes()
+	defer cancel()
+
+	node := "fake:1"
+	pinged := func() bool {
+		d.nodeMonitor.lock.Lock()
+		defer d.nodeMonitor.lock.Unlock()
+		return !d.nodeMonitor.nodes[node].pinging
+	}
+	tick := func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		// The heartbeat rearmed its timer once it started the ping
+		clock.BlockUntil(1)
+		waitFor(t, "heartbeat", pinged)
+	}
+
+	d.MonitorNode(node)
+	defer d.DemonitorNode(node)
+	expectNodeEvent(t, events, node, NodeUp)
+	for i := 0; i < 10; i++ {
+		tick()
+	}
+	if phi := d.Suspicion(node); phi > 1 {
+		t.Errorf("Expected low suspicion but got %v\n", phi)
+	}
+
+	// Late heartbeats within the acceptable pause are tolerated
+	transport.setMode(heartbeatDelay)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	clock.BlockUntil(1)
+	transport.setMode(heartbeatAnswer)
+	waitFor(t, "delayed heartbeat", pinged)
+	tick()
+	expectNoNodeEvent(t, events)
+
+	transport.setMode(heartbeatDrop)
+	for i := 0; i < 4; i++ {
+		tick()
+	}
+	expectNoNodeEvent(t, events)
+	for i := 0; i < 4; i++ {
+		tick()
+	}
+	event := expectNodeEvent(t, events, node, NodeDown)
+	if !strings.HasPrefix(event.Reason, "suspected") {
+		t.Errorf("Expected suspected but got %q\n", event.Reason)
+	}
+
+	transport.setMode(heartbeatAnswer)
+	tick()
+	expectNodeEvent(t, events, node, NodeUp)
+	if phi := d.Suspicion(node); phi > 1 {
+		t.Errorf("Expected suspicion to be reset but got %v\n", phi)
+	}
+}
@@ -0,0 +1,232 @@
+package cine
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// rpcClient is the part of *rpc.Client used to send messages to other
+// directors.
+type rpcClient interface {
+	Go(serviceMethod string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call
+	Close() error
+}
+
+// clientWrapper is implemented by transports that intercept the messages sent
+// from one director to another.
+type clientWrapper interface {
+	wrapClient(from string, to string, client rpcClient, clock Clock) rpcClient
+}
+
+// nodeDialer is implemented by transports that decide whether a director can
+// connect to another one.
+type nodeDialer interface {
+	dialFrom(from string, addr string, timeout time.Duration) (net.Conn, error)
+}
+
+var errPartitioned = errors.New("cine: faulty network partitioned")
+
+// FaultyNetwork is a Transport injecting faults into the messages between
+// directors: partitions, latency and jitter, drops, duplicates and reordering.
+// Faults are decided by a seeded random generator. Delays follow the clock of
+// the sending director.
+//
+// Requests and replies are faulted alike. A dropped or partitioned message is
+// never delivered, so the caller waits for the call timeout of its director or
+// the deadline of its context, as it would on a real network. Messages in
+// flight when a partition starts are lost. Connections cannot be made across
+// a partition.
+type FaultyNetwork struct {
+	Transport
+
+	lock          sync.Mutex
+	rand          *rand.Rand
+	groups        map[string]int
+	latency       time.Duration
+	jitter        time.Duration
+	dropRate      float64
+	duplicateRate float64
+	reorderRate   float64
+	reorderDelay  time.Duration
+}
+
+// NewFaultyNetwork wraps transport. Until faults are configured messages are
+// delivered as is.
+func NewFaultyNetwork(transport Transport, seed int64) *FaultyNetwork {
+	return &FaultyNetwork{
+		Transport: transport,
+		rand:      rand.New(rand.NewSource(seed)),
+		groups:    make(map[string]int),
+	}
+}
+
+// Partition splits the nodes in groups that cannot reach each other. Nodes
+// that are in no group reach every node.
+func (n *FaultyNetwork) Partition(groups ...[]string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.groups = make(map[string]int)
+	for i, group := range groups {
+		for _, node := range group {
+			n.groups[node] = i + 1
+		}
+	}
+}
+
+// Heal removes all partitions.
+func (n *FaultyNetwork) Heal() {
+	n.Partition()
+}
+
+// SetLatency delays every message by latency plus a random duration up to
+// jitter.
+func (n *FaultyNetwork) SetLatency(latency time.Duration, jitter time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.latency = latency
+	n.jitter = jitter
+}
+
+// SetDropRate sets the fraction of messages that are lost.
+func (n *FaultyNetwork) SetDropRate(rate float64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.dropRate = rate
+}
+
+// SetDuplicateRate sets the fraction of messages that are delivered twice.
+func (n *FaultyNetwork) SetDuplicateRate(rate float64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.duplicateRate = rate
+}
+
+// SetReorderRate sets the fraction of messages held back by delay so that the
+// following messages overtake them.
+func (n *FaultyNetwork) SetReorderRate(rate float64, delay time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.reorderRate = rate
+	n.reorderDelay = delay
+}
+
+// canReach must be called within n.lock critical section
+func (n *FaultyNetwork) canReach(from string, to string) bool {
+	a, b := n.groups[from], n.groups[to]
+	return a == 0 || b == 0 || a == b
+}
+
+// reachable returns true if from and to are not partitioned.
+func (n *FaultyNetwork) reachable(from string, to string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.canReach(from, to)
+}
+
+func (n *FaultyNetwork) dialFrom(from string, addr string, timeout time.Duration) (net.Conn, error) {
+	if !n.reachable(from, addr) {
+		return nil, &net.OpError{Op: "dial", Net: "faulty", Err: errPartitioned}
+	}
+	return n.Transport.Dial(addr, timeout)
+}
+
+type deliveryPlan struct {
+	drop      bool
+	duplicate bool
+	delay     time.Duration
+}
+
+// plan decides the fate of a message from one node to another.
+func (n *FaultyNetwork) plan(from string, to string) deliveryPlan {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	var p deliveryPlan
+	if !n.canReach(from, to) || n.rand.Float64() < n.dropRate {
+		p.drop = true
+		return p
+	}
+	p.duplicate = n.rand.Float64() < n.duplicateRate
+	p.delay = n.latency
+	if n.jitter > 0 {
+		p.delay += time.Duration(n.rand.Int63n(int64(n.jitter)))
+	}
+	if n.rand.Float64() < n.reorderRate {
+		p.delay += n.reorderDelay
+	}
+	return p
+}
+
+func (n *FaultyNetwork) wrapClient(from string, to string, client rpcClient, clock Clock) rpcClient {
+	return &faultyClient{client, n, from, to, clock}
+}
+
+type faultyClient struct {
+	client  rpcClient
+	network *FaultyNetwork
+	from    string
+	to      string
+	clock   Clock
+}
+
+func (c *faultyClient) Go(serviceMethod string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call {
+	if done == nil {
+		done = make(chan *rpc.Call, 1)
+	}
+	call := &rpc.Call{ServiceMethod: serviceMethod, Args: args, Reply: reply, Done: done}
+	p := c.network.plan(c.from, c.to)
+	if p.drop {
+		return call
+	}
+
+	send := func() {
+		if !c.network.reachable(c.from, c.to) {
+			return
+		}
+		inner := c.client.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+		if p.duplicate {
+			// The reply to the duplicate is discarded
+			dupReply := reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+			c.client.Go(serviceMethod, args, dupReply, nil)
+		}
+		go func() {
+			<-inner.Done
+			c.reply(call, inner)
+		}()
+	}
+	c.after(p.delay, send)
+	return call
+}
+
+// reply delivers the reply of inner to call, unless it is lost on the way
+// back.
+func (c *faultyClient) reply(call *rpc.Call, inner *rpc.Call) {
+	p := c.network.plan(c.to, c.from)
+	if p.drop {
+		return
+	}
+	c.after(p.delay, func() {
+		if !c.network.reachable(c.to, c.from) {
+			return
+		}
+		call.Error = inner.Error
+		call.Done <- call
+	})
+}
+
+func (c *faultyClient) after(delay time.Duration, f func()) {
+	if delay > 0 {
+		c.clock.AfterFunc(delay, f)
+	} else {
+		f()
+	}
+}
+
+func (c *faultyClient) Close() error {
+	return c.client.Close()
+}
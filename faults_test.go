@@ -0,0 +1,182 @@
+package cine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type Recorder struct {
+	Actor
+	lock sync.Mutex
+	seen []int
+}
+
+func (r *Recorder) Record(i int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.seen = append(r.seen, i)
+}
+
+func (r *Recorder) Seen() []int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]int(nil), r.seen...)
+}
+
+func (r *Recorder) ExposedMethods() []string {
+	return []string{"Record"}
+}
+
+func (r *Recorder) Terminate(errReason error) {
+}
+
+func newFaultyCluster(t *testing.T, seed int64, names ...string) (*FaultyNetwork, []*Director) {
+	network := NewFaultyNetwork(NewMemoryNetwork(), seed)
+	var directors []*Director
+	for _, name := range names {
+		d := mustNewDirector(t, name, WithTransport(network), WithCallTimeout(100*time.Millisecond))
+		directors = append(directors, d)
+	}
+	return network, directors
+}
+
+func TestFaultyNetworkPlan(t *testing.T) {
+	plans := func() []deliveryPlan {
+		n := NewFaultyNetwork(NewMemoryNetwork(), 42)
+		n.SetLatency(time.Millisecond, 10*time.Millisecond)
+		n.SetDropRate(0.3)
+		n.SetDuplicateRate(0.3)
+		var plans []deliveryPlan
+		for i := 0; i < 100; i++ {
+			plans = append(plans, n.plan("a:1", "b:1"))
+		}
+		return plans
+	}
+	first, second := plans(), plans()
+	drops := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same seed to give the same faults but got %v and %v\n", first[i], second[i])
+		}
+		if first[i].drop {
+			drops += 1
+		}
+	}
+	if drops == 0 || drops == len(first) {
+		t.Errorf("Expected some drops but got %d\n", drops)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	network, directors := newFaultyCluster(t, 1, "a:1", "b:1", "c:1", "d:1")
+	a, b, c, d := directors[0], directors[1], directors[2], directors[3]
+	pid := b.StartActor(&Counter{})
+	defer b.Stop(pid)
+	if _, err := a.Call(pid, (*Counter).Key); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+
+	network.Partition([]string{"a:1", "d:1"}, []string{"b:1"})
+	// Messages on the existing connection are lost
+	if _, err := a.Call(pid, (*Counter).Incr); err != ErrCallTimeout {
+		t.Errorf("Expected ErrCallTimeout but got %v\n", err)
+	}
+	// New connections cannot be made
+	if _, err := d.Call(pid, (*Counter).Incr); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+	// c is in no group and reaches everybody
+	if _, err := c.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	network.Heal()
+	r, err := a.Call(pid, (*Counter).Incr)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(int) != 2 {
+		t.Errorf("Expected the partitioned call not to be delivered but got %v\n", r[0])
+	}
+}
+
+func TestDropAndDuplicate(t *testing.T) {
+	network, directors := newFaultyCluster(t, 1, "a:1", "b:1")
+	a, b := directors[0], directors[1]
+	pid := b.StartActor(&Counter{})
+	defer b.Stop(pid)
+
+	network.SetDropRate(1)
+	if _, err := a.Call(pid, (*Counter).Incr); err != ErrCallTimeout {
+		t.Errorf("Expected ErrCallTimeout but got %v\n", err)
+	}
+
+	network.SetDropRate(0)
+	network.SetDuplicateRate(1)
+	recorder := &Recorder{}
+	recorderPid := b.StartActor(recorder)
+	defer b.Stop(recorderPid)
+	a.Call(recorderPid, (*Recorder).Record, 1)
+	waitFor(t, "duplicate", func() bool { return len(recorder.Seen()) == 2 })
+}
+
+func TestDroppedReply(t *testing.T) {
+	network := NewFaultyNetwork(NewMemoryNetwork(), 1)
+	a := mustNewDirector(t, "a:1", WithTransport(network), WithCallTimeout(time.Second))
+	b := mustNewDirector(t, "b:1", WithTransport(network))
+	recorder := &Recorder{}
+	pid := b.StartActor(recorder)
+	defer b.Stop(pid)
+
+	network.SetLatency(100*time.Millisecond, 0)
+	result := make(chan *DirectorError, 1)
+	go func() {
+		_, err := a.Call(pid, (*Recorder).Record, 1)
+		result <- err
+	}()
+	// The request arrived, the reply is lost on the way back
+	waitFor(t, "request", func() bool { return len(recorder.Seen()) == 1 })
+	network.Partition([]string{"a:1"}, []string{"b:1"})
+	if err := <-result; err != ErrCallTimeout {
+		t.Errorf("Expected ErrCallTimeout but got %v\n", err)
+	}
+}
+
+func TestDroppedCallWithContext(t *testing.T) {
+	network := NewFaultyNetwork(NewMemoryNetwork(), 1)
+	a := mustNewDirector(t, "a:1", WithTransport(network), WithCallTimeout(0))
+	b := mustNewDirector(t, "b:1", WithTransport(network))
+	book := &Phonebook{Actor{}, make(map[string]int)}
+	pid := b.StartActor(book)
+	defer b.Stop(pid)
+
+	network.SetDropRate(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := a.CallWithContext(pid, (*Phonebook).Sleep, ctx, "1s")
+	if err == nil || !strings.HasPrefix(err.Error(), "context") {
+		t.Errorf("Expected a context error but got %v\n", err)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	network, directors := newFaultyCluster(t, 7, "a:1", "b:1")
+	a, b := directors[0], directors[1]
+	recorder := &Recorder{}
+	pid := b.StartActor(recorder)
+	defer b.Stop(pid)
+
+	network.SetReorderRate(0.5, 20*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		a.Cast(pid, nil, (*Recorder).Record, i)
+	}
+	waitFor(t, "all messages", func() bool { return len(recorder.Seen()) == 20 })
+	if sort.IntsAreSorted(recorder.Seen()) {
+		t.Errorf("Expected messages to be reordered but got %v\n", recorder.Seen())
+	}
+}
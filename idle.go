@@ -0,0 +1,147 @@
+package cine
+
+import "time"
+
+// Idler is implemented by actors that want to be notified when no message
+// arrived within the idle timeout. Actors that do not implement Idler are
+// stopped with ErrActorIdle instead, unless hibernation is enabled.
+type Idler interface {
+	Idle()
+}
+
+// actorIdle holds the idle timeout state of an actor. Apart from ch, it is only
+// accessed within the actor thread.
+type actorIdle struct {
+	timeout    time.Duration
+	hibernate  bool
+	lastActive time.Time
+	timer      Timer
+	ch         chan bool
+}
+
+// SetIdleTimeout sets the duration after which an actor without incoming
+// messages is considered idle. Zero disables the idle timeout. It must be
+// called before the actor is started or from within the actor thread.
+func (r *Actor) SetIdleTimeout(d time.Duration) {
+	r.idle.timeout = d
+	if r.idle.ch != nil {
+		// Already running, rearm with the new timeout
+		r.stopIdleTimer()
+		r.startIdleTimer()
+	}
+}
+
+// SetHibernate makes an idle actor hibernate instead of stopping. A hibernated
+// actor releases its goroutines and message queue and is transparently woken
+// up by the next message. It must be called before the actor is started or from
+// within the actor thread.
+func (r *Actor) SetHibernate(enabled bool) {
+	r.idle.hibernate = enabled
+}
+
+func (r *Actor) startIdleTimer() {
+	r.idle.lastActive = r.clock().Now()
+	if r.idle.timeout > 0 {
+		r.armIdleTimer(r.idle.timeout)
+	}
+}
+
+func (r *Actor) armIdleTimer(d time.Duration) {
+	ch := r.idle.ch
+	r.idle.timer = r.clock().AfterFunc(d, func() {
+		select {
+		case ch <- true:
+		default:
+		}
+	})
+}
+
+func (r *Actor) stopIdleTimer() {
+	if r.idle.timer != nil {
+		r.idle.timer.Stop()
+		r.idle.timer = nil
+	}
+}
+
+// checkIdle is called within the actor thread when the idle timer fires. The
+// timer is not reset on every message; instead the elapsed time since the last
+// message is checked here and the timer is rearmed for the remainder. It
+// returns true if the actor hibernated and the message loop must exit.
+func (r *Actor) checkIdle() bool {
+	if r.idle.timer == nil {
+		// Stale signal from a stopped timer
+		return false
+	}
+	if r.suspended {
+		// Suspended actors wait for Resume, they are not idle
+		r.armIdleTimer(r.idle.timeout)
+		return false
+	}
+	elapsed := r.clock().Now().Sub(r.idle.lastActive)
+	if elapsed < r.idle.timeout {
+		r.armIdleTimer(r.idle.timeout - elapsed)
+		return false
+	}
+
+	idler, isIdler := r.receiver.Interface().(Idler)
+	if isIdler {
+		idler.Idle()
+	}
+	if r.idle.hibernate {
+		if r.tryHibernate() {
+			return true
+		}
+	} else if !isIdler {
+		if r.stopIdle() {
+			return false
+		}
+	}
+	r.startIdleTimer()
+	return false
+}
+
+// tryHibernate stops the message queue if nobody is sending a message to the
+// actor. Must be called within the actor thread.
+func (r *Actor) tryHibernate() bool {
+	r.aliveLock.Lock()
+	defer r.aliveLock.Unlock()
+
+	if !r.alive || r.inflight > 0 || r.queue.Len() > 0 {
+		return false
+	}
+	r.stopIdleTimer()
+	r.queue.Stop <- true
+	r.queue = nil
+	r.hibernated = true
+	return true
+}
+
+// stopIdle stops the actor with ErrActorIdle unless a message is on its way.
+// Must be called within the actor thread.
+func (r *Actor) stopIdle() bool {
+	r.aliveLock.Lock()
+	if !r.alive || r.inflight > 0 || r.queue.Len() > 0 {
+		r.aliveLock.Unlock()
+		return false
+	}
+	// Nobody can get the queue anymore
+	r.alive = false
+	r.aliveLock.Unlock()
+	r.terminateActor(ErrActorIdle)
+	return true
+}
+
+// wake restarts the message loop of a hibernated actor. Must be called within
+// r.aliveLock critical section.
+func (r *Actor) wake() {
+	r.queue = NewMessageQueue(kActorQueueLength)
+	r.hibernated = false
+	go r.messageLoop()
+}
+
+// Hibernated returns true if the actor is hibernated.
+func (r *Actor) Hibernated() bool {
+	r.aliveLock.Lock()
+	defer r.aliveLock.Unlock()
+	return r.hibernated
+}
@@ -0,0 +1,209 @@
+package cine
+
+import (
+	"testing"
+	"time"
+)
+
+type IdleActor struct {
+	Actor
+	reasons chan error
+	x       int
+}
+
+func (a *IdleActor) Get() int {
+	return a.x
+}
+
+func (a *IdleActor) Terminate(errReason error) {
+	a.reasons <- errReason
+}
+
+type IdlerActor struct {
+	IdleActor
+	idles chan bool
+}
+
+func (a *IdlerActor) Idle() {
+	a.idles <- true
+}
+
+// waitFor polls cond until it returns true. Idle handling happens in the
+// actor thread after the fake clock fires.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s\n", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newIdleTestDirector(t *testing.T) (*Director, *FakeClock) {
+	d := newTestDirector(t)
+	clock := NewFakeClock(time.Now())
+	d.SetClock(clock)
+	return d, clock
+}
+
+func TestIdleTimeoutStopsActor(t *testing.T) {
+	d, clock := newIdleTestDirector(t)
+	a := &IdleActor{Actor{}, make(chan error, 1), 1}
+	a.SetIdleTimeout(time.Minute)
+	pid := d.StartActor(a)
+
+	clock.BlockUntil(1)
+	clock.Advance(30 * time.Second)
+	if _, err := d.Call(pid, (*IdleActor).Get); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	// Only 40 seconds passed since the last message
+	clock.Advance(40 * time.Second)
+	clock.BlockUntil(1)
+	if _, err := d.localActorFromPid(pid); err != nil {
+		t.Fatalf("Actor stopped before idle timeout: %v\n", err)
+	}
+
+	clock.Advance(20 * time.Second)
+	select {
+	case reason := <-a.reasons:
+		if reason != ErrActorIdle {
+			t.Errorf("Expected ErrActorIdle but got %v\n", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Actor was not stopped")
+	}
+	if _, err := d.localActorFromPid(pid); err != ErrActorNotFound {
+		t.Errorf("Expected actor removed from director but got %v\n", err)
+	}
+}
+
+func TestIdleCallback(t *testing.T) {
+	d, clock := newIdleTestDirector(t)
+	a := &IdlerActor{IdleActor{Actor{}, make(chan error, 1), 1}, make(chan bool, 1)}
+	a.SetIdleTimeout(time.Minute)
+	pid := d.StartActor(a)
+	defer d.Stop(pid)
+
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		select {
+		case <-a.idles:
+		case <-time.After(time.Second):
+			t.Fatal("Idle was not called")
+		}
+	}
+	if _, err := d.Call(pid, (*IdlerActor).Get); err != nil {
+		t.Errorf("Expected idler to keep running but got %v\n", err)
+	}
+}
+
+func TestHibernate(t *testing.T) {
+	d, clock := newIdleTestDirector(t)
+	a := &IdleActor{Actor{}, make(chan error, 1), 5}
+	a.SetIdleTimeout(time.Minute)
+	a.SetHibernate(true)
+	pid := d.StartActor(a)
+	defer d.Stop(pid)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	waitFor(t, "hibernation", a.Hibernated)
+	if a.queue != nil {
+		t.Error("Expected message queue to be released")
+	}
+
+	r, err := d.Call(pid, (*IdleActor).Get)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(int) != 5 {
+		t.Errorf("Expected 5 but got %v\n", r[0])
+	}
+	if a.Hibernated() {
+		t.Error("Expected actor to wake up")
+	}
+
+	// Hibernates again after another idle period
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	waitFor(t, "second hibernation", a.Hibernated)
+
+	// Stopping a hibernated actor terminates it normally
+	d.Stop(pid)
+	select {
+	case reason := <-a.reasons:
+		if reason != ErrActorStop {
+			t.Errorf("Expected ErrActorStop but got %v\n", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Hibernated actor was not stopped")
+	}
+}
+
+func TestIdleTimeoutWithMessageInFlight(t *testing.T) {
+	d, clock := newIdleTestDirector(t)
+	a := &IdleActor{Actor{}, make(chan error, 1), 1}
+	a.SetIdleTimeout(time.Minute)
+	d.StartActor(a)
+	clock.BlockUntil(1)
+
+	// A sender got the queue when the actor became idle
+	queue, ok := a.acquireQueue()
+	if !ok {
+		t.Fatal("Expected the actor to be alive")
+	}
+	clock.Advance(2 * time.Minute)
+	clock.BlockUntil(1)
+	done := make(chan *ActorCall, 1)
+	a.runInThread(queue, done, SpanContext{}, a.receiver, (*IdleActor).Get)
+	a.releaseQueue()
+	select {
+	case call, ok := <-done:
+		if !ok || call.ReplyAsInterfaces()[0].(int) != 1 {
+			t.Errorf("Expected the message to be handled but got %v\n", call)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Message sent to an idle actor was lost")
+	}
+
+	clock.Advance(2 * time.Minute)
+	select {
+	case reason := <-a.reasons:
+		if reason != ErrActorIdle {
+			t.Errorf("Expected ErrActorIdle but got %v\n", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Actor was not stopped")
+	}
+}
+
+func TestIdleStopWithConcurrentCalls(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		a := &IdleActor{Actor{}, make(chan error, 1), 1}
+		a.SetIdleTimeout(time.Millisecond)
+		a.startMessageLoop(a)
+
+		done := make(chan bool)
+		for j := 0; j < 10; j++ {
+			go func() {
+				// Calls fail once the actor stopped but never hang
+				for k := 0; k < 20; k++ {
+					a.call(SpanContext{}, (*IdleActor).Get)
+					time.Sleep(time.Duration(k%3) * time.Millisecond)
+				}
+				done <- true
+			}()
+		}
+		for j := 0; j < 10; j++ {
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Calls racing with the idle stop hung")
+			}
+		}
+		a.stop()
+	}
+}
@@ -0,0 +1,132 @@
+package cine
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ActorInfo describes a running actor.
+type ActorInfo struct {
+	Pid Pid
+	// Type is the type of the actor implementation, like *main.Phonebook
+	Type string
+	// Name is the identity of virtual actors, empty for other actors
+	Name string
+	// Mailbox is the number of messages waiting to be handled
+	Mailbox    int
+	Uptime     time.Duration
+	Processed  int64
+	Hibernated bool
+	Suspended  bool
+	// Method is the method being handled, empty if the actor is waiting for
+	// a message
+	Method string
+	// Links are the actors linked with this one and Monitors the actors
+	// monitoring it
+	Links    []Pid
+	Monitors []Pid
+}
+
+type ActorsRequest struct{}
+
+type ActorsResponse struct {
+	Err    *DirectorError
+	Actors []ActorInfo
+}
+
+// kActorsMethod is the method name introspection requests are authorized with.
+const kActorsMethod = "Actors"
+
+// Actors returns information about every local actor, ordered by pid.
+func (d *Director) Actors() []ActorInfo {
+	d.pidLock.RLock()
+	actors := make([]*Actor, 0, len(d.pidMap))
+	for _, actor := range d.pidMap {
+		actors = append(actors, actor)
+	}
+	d.pidLock.RUnlock()
+	sort.Slice(actors, func(i, j int) bool { return actors[i].pid.ActorId < actors[j].pid.ActorId })
+
+	now := d.clock.Now()
+	infos := make([]ActorInfo, 0, len(actors))
+	for _, actor := range actors {
+		info := actor.info(now)
+		d.supervision.lock.Lock()
+		info.Links = append([]Pid(nil), d.supervision.links[actor.pid]...)
+		info.Monitors = append([]Pid(nil), d.supervision.monitors[actor.pid]...)
+		d.supervision.lock.Unlock()
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// ActorsOf returns information about the actors of node, which may be the
+// local node. The pids of another node come without capability tokens.
+func (d *Director) ActorsOf(node string) ([]ActorInfo, *DirectorError) {
+	if node == d.nodeName {
+		return d.Actors(), nil
+	}
+	var resp ActorsResponse
+	if err := d.remoteCall(node, "HandleActors", ActorsRequest{}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != nil {
+		return nil, canonicalError(resp.Err)
+	}
+	return resp.Actors, nil
+}
+
+func (d *DirectorApi) HandleActors(req ActorsRequest, reply *ActorsResponse) error {
+	if err := d.authorize(Pid{NodeName: d.director.nodeName}, kActorsMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	reply.Actors = redactActors(d.director.Actors())
+	return nil
+}
+
+// redactActors removes the capability tokens from pids. Pids handed to other
+// nodes or to the debug view must not grant access to the actors.
+func redactActors(infos []ActorInfo) []ActorInfo {
+	for i := range infos {
+		infos[i].Pid.Token = ""
+		infos[i].Links = redactPids(infos[i].Links)
+		infos[i].Monitors = redactPids(infos[i].Monitors)
+	}
+	return infos
+}
+
+func redactPids(pids []Pid) []Pid {
+	redacted := make([]Pid, len(pids))
+	for i, pid := range pids {
+		pid.Token = ""
+		redacted[i] = pid
+	}
+	return redacted
+}
+
+func (r *Actor) info(now time.Time) ActorInfo {
+	info := ActorInfo{
+		Pid:       r.pid,
+		Type:      r.receiver.Type().String(),
+		Uptime:    now.Sub(r.startedAt),
+		Processed: atomic.LoadInt64(&r.processed),
+	}
+	if r.identity.Kind != "" {
+		info.Name = r.identity.String()
+	}
+	if current, ok := r.current.Load().(reflect.Value); ok && current.IsValid() {
+		info.Method = functionName(current)
+	}
+	// The queue is replaced when the actor hibernates and wakes up
+	r.aliveLock.Lock()
+	info.Hibernated = r.hibernated
+	info.Suspended = r.suspended
+	if r.queue != nil {
+		info.Mailbox = r.queue.Len()
+	}
+	r.aliveLock.Unlock()
+	return info
+}
@@ -0,0 +1,111 @@
+package cine
+
+import (
+	"testing"
+)
+
+func TestActors(t *testing.T) {
+	network := NewMemoryNetwork()
+	d := mustNewDirector(t, "node:1", WithTransport(network))
+	d.RegisterKind(Kind{Name: "counter", Factory: newCounter})
+	actor := &LimitActor{release: make(chan bool)}
+	pid := d.StartActor(actor)
+	defer d.Stop(pid)
+	watcher := d.StartActor(&Watcher{Actor{}, make(chan string, 1)})
+	defer d.Stop(watcher)
+	d.Monitor(watcher, pid)
+	d.Link(pid, watcher)
+	counter, _ := d.Activate(Identity{"counter", "a"})
+
+	d.Cast(pid, nil, (*LimitActor).Wait)
+	d.Cast(pid, nil, (*LimitActor).Wait)
+	var infos []ActorInfo
+	waitFor(t, "queued message", func() bool {
+		infos = d.Actors()
+		return len(infos) == 3 && infos[0].Mailbox == 1
+	})
+	info := infos[0]
+	if info.Pid != pid || info.Type != "*cine.LimitActor" || info.Method != "Wait" {
+		t.Errorf("Expected %v busy in Wait but got %+v\n", pid, info)
+	}
+	if len(info.Links) != 1 || info.Links[0] != watcher {
+		t.Errorf("Expected a link with %v but got %v\n", watcher, info.Links)
+	}
+	if len(info.Monitors) != 1 || info.Monitors[0] != watcher {
+		t.Errorf("Expected a monitor by %v but got %v\n", watcher, info.Monitors)
+	}
+	if infos[1].Pid != watcher || len(infos[1].Links) != 1 || infos[1].Links[0] != pid {
+		t.Errorf("Expected %v linked with %v but got %+v\n", watcher, pid, infos[1])
+	}
+	if infos[2].Pid != counter || infos[2].Name != "counter/a" {
+		t.Errorf("Expected the counter/a activation but got %+v\n", infos[2])
+	}
+
+	actor.release <- true
+	actor.release <- true
+	waitFor(t, "processed messages", func() bool {
+		info := d.Actors()[0]
+		return info.Processed == 2 && info.Method == "" && info.Mailbox == 0
+	})
+
+	// The same from another node
+	other := mustNewDirector(t, "other:1", WithTransport(network))
+	remoteInfos, err := other.ActorsOf("node:1")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if len(remoteInfos) != 3 || remoteInfos[0].Pid != pid || remoteInfos[0].Processed != 2 {
+		t.Errorf("Expected the actors of node:1 but got %+v\n", remoteInfos)
+	}
+}
+
+func TestActorsAuthorized(t *testing.T) {
+	network := NewMemoryNetwork()
+	mustNewDirector(t, "node:1", WithTransport(network),
+		WithAuthorizer(func(node string, pid Pid, method string) bool {
+			return method != "Actors"
+		}))
+	other := mustNewDirector(t, "other:1", WithTransport(network))
+	if _, err := other.ActorsOf("node:1"); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+}
+
+func TestActorsOfRedactsTokens(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "node:1", WithTransport(network), WithCapabilities())
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+	watcher := remoteD.StartActor(&Watcher{downs: make(chan string, 1)})
+	defer remoteD.Stop(watcher)
+	remoteD.Monitor(watcher, pid)
+	remoteD.Link(pid, watcher)
+	if pid.Token == "" {
+		t.Fatal("Expected a capability token")
+	}
+
+	other := mustNewDirector(t, "other:1", WithTransport(network))
+	infos, err := other.ActorsOf("node:1")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 actors but got %+v\n", infos)
+	}
+	for _, info := range infos {
+		pids := append([]Pid{info.Pid}, info.Links...)
+		pids = append(pids, info.Monitors...)
+		for _, p := range pids {
+			if p.Token != "" {
+				t.Errorf("Expected the capability token to be redacted but got %+v\n", info)
+			}
+		}
+	}
+	if len(infos[0].Links) != 1 || len(infos[0].Monitors) != 1 {
+		t.Errorf("Expected a link and a monitor but got %+v\n", infos[0])
+	}
+	// Local listings keep the tokens
+	if local := remoteD.Actors(); local[0].Pid != pid {
+		t.Errorf("Expected %v but got %v\n", pid, local[0].Pid)
+	}
+}
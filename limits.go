@@ -0,0 +1,181 @@
+package cine
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	kDefaultMaxArgs         = 64
+	kDefaultMaxArgDepth     = 32
+	kDefaultMaxPeerRequests = 1024
+	kDefaultMaxRemoteQueued = 10000
+)
+
+// WithMaxArgs sets the maximum number of arguments of a remote request.
+// Larger requests fail with ErrTooManyArgs. Zero means no limit.
+func WithMaxArgs(n int) Option {
+	return func(d *Director) {
+		d.config.maxArgs = n
+	}
+}
+
+// WithMaxArgDepth sets how deep slices, maps and structs can be nested in the
+// arguments of a remote request. Deeper requests fail with ErrArgsTooDeep.
+// The depth is checked after the request is decoded; WithMaxMessageSize bounds
+// the decoding itself. Zero means no limit.
+func WithMaxArgDepth(depth int) Option {
+	return func(d *Director) {
+		d.config.maxArgDepth = depth
+	}
+}
+
+// WithMaxPeerRequests sets how many remote requests of a node can be handled
+// at the same time, counting casts until they are processed. Other requests
+// fail with ErrTooManyRequests. Zero means no limit. Nodes whose name is not
+// authenticated are counted per host.
+func WithMaxPeerRequests(n int) Option {
+	return func(d *Director) {
+		d.config.maxPeerRequests = n
+	}
+}
+
+// WithMaxRemoteQueued sets how many messages from other nodes can be queued
+// for a single actor, including the messages held while it is suspended.
+// Other requests fail with ErrMailboxFull. Zero means no limit.
+func WithMaxRemoteQueued(n int) Option {
+	return func(d *Director) {
+		d.config.maxRemoteQueued = n
+	}
+}
+
+// peerRequests counts the remote requests being handled for each node.
+type peerRequests struct {
+	lock     sync.Mutex
+	inflight map[string]int
+}
+
+func (p *peerRequests) acquire(peer string, limit int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if limit > 0 && p.inflight[peer] >= limit {
+		return false
+	}
+	p.inflight[peer] += 1
+	return true
+}
+
+func (p *peerRequests) release(peer string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inflight[peer] -= 1
+	if p.inflight[peer] == 0 {
+		delete(p.inflight, peer)
+	}
+}
+
+// acquireRemote reserves a place in the queue of the actor for a message from
+// another node.
+func (r *Actor) acquireRemote(limit int) bool {
+	if atomic.AddInt64(&r.remoteQueued, 1) > int64(limit) && limit > 0 {
+		atomic.AddInt64(&r.remoteQueued, -1)
+		return false
+	}
+	return true
+}
+
+func (r *Actor) releaseRemote() {
+	atomic.AddInt64(&r.remoteQueued, -1)
+}
+
+// admit reserves the resources of a remote request to actor. release must be
+// called once the request was processed.
+func (d *DirectorApi) admit(actor *Actor) (func(), *DirectorError) {
+	config := &d.director.config
+	peer := d.peerKey()
+	if !d.director.peerRequests.acquire(peer, config.maxPeerRequests) {
+		d.director.logger.Warnln("Too many requests from", peer)
+		return nil, ErrTooManyRequests
+	}
+	if !actor.acquireRemote(config.maxRemoteQueued) {
+		d.director.peerRequests.release(peer)
+		d.director.logger.Warnln("Mailbox of", actor.pid, "is full, rejecting request from", peer)
+		return nil, ErrMailboxFull
+	}
+	release := func() {
+		actor.releaseRemote()
+		d.director.peerRequests.release(peer)
+	}
+	return release, nil
+}
+
+// checkArgs checks the arguments of a remote request against the limits of
+// the director. The request is already decoded at this point.
+func (d *DirectorApi) checkArgs(args []interface{}) *DirectorError {
+	config := &d.director.config
+	if config.maxArgs > 0 && len(args) > config.maxArgs {
+		return ErrTooManyArgs
+	}
+	if config.maxArgDepth > 0 {
+		for _, arg := range args {
+			if tooDeep(reflect.ValueOf(arg), config.maxArgDepth) {
+				return ErrArgsTooDeep
+			}
+		}
+	}
+	return nil
+}
+
+// tooDeep returns true if more than depth slices, arrays, maps or structs are
+// nested in v. Pointers and interfaces do not count.
+func tooDeep(v reflect.Value, depth int) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return false
+		}
+		return tooDeep(v.Elem(), depth)
+	case reflect.Slice, reflect.Array:
+		if depth == 0 {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if tooDeep(v.Index(i), depth-1) {
+				return true
+			}
+		}
+	case reflect.Map:
+		if depth == 0 {
+			return true
+		}
+		for _, key := range v.MapKeys() {
+			if tooDeep(key, depth-1) || tooDeep(v.MapIndex(key), depth-1) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		if depth == 0 {
+			return true
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if tooDeep(v.Field(i), depth-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validArgs returns true if function can be called on actor with args. Local
+// callers get a panic for invalid arguments, but a remote request must not
+// bring the node down.
+func validArgs(actor *Actor, function interface{}, args []interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	actor.verifyCallSignature(function, args)
+	return true
+}
@@ -0,0 +1,119 @@
+package cine
+
+import (
+	"encoding/gob"
+	"sync/atomic"
+	"testing"
+)
+
+type LimitActor struct {
+	Actor
+	release chan bool
+}
+
+func (a *LimitActor) Depth(v [][][]int) int {
+	return len(v)
+}
+
+func (a *LimitActor) Wait() {
+	<-a.release
+}
+
+func (a *LimitActor) ExposedMethods() []string {
+	return []string{"Depth", "Wait"}
+}
+
+func (a *LimitActor) Terminate(errReason error) {
+}
+
+func TestArgumentLimits(t *testing.T) {
+	gob.Register([][][]int{})
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithMaxArgs(1), WithMaxArgDepth(2))
+	pid := remoteD.StartActor(&LimitActor{})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	if _, err := d.Call(pid, (*LimitActor).Depth, [][][]int{}); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*LimitActor).Depth, [][][]int{{{1}}}); err != ErrArgsTooDeep {
+		t.Errorf("Expected ErrArgsTooDeep but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*LimitActor).Depth, 1, 2); err != ErrTooManyArgs {
+		t.Errorf("Expected ErrTooManyArgs but got %v\n", err)
+	}
+	// Arguments not matching the method do not bring the node down
+	if _, err := d.Call(pid, (*LimitActor).Depth, "wrong"); err != ErrInvalidArgs {
+		t.Errorf("Expected ErrInvalidArgs but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*LimitActor).Depth); err != ErrInvalidArgs {
+		t.Errorf("Expected ErrInvalidArgs but got %v\n", err)
+	}
+}
+
+func TestPeerRequestLimit(t *testing.T) {
+	// Requests are counted per node once node names are authenticated
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithCookie("secret"),
+		WithMaxPeerRequests(2))
+	actor := &LimitActor{release: make(chan bool)}
+	pid := remoteD.StartActor(actor)
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "local:1", WithTransport(network), WithCookie("secret"))
+	d.Cast(pid, nil, (*LimitActor).Wait)
+	d.Cast(pid, nil, (*LimitActor).Wait)
+	inflight := func(peer string) int {
+		remoteD.peerRequests.lock.Lock()
+		defer remoteD.peerRequests.lock.Unlock()
+		return remoteD.peerRequests.inflight[peer]
+	}
+	waitFor(t, "casts", func() bool { return inflight("local:1") == 2 })
+	if _, err := d.Call(pid, (*LimitActor).Wait); err != ErrTooManyRequests {
+		t.Errorf("Expected ErrTooManyRequests but got %v\n", err)
+	}
+
+	// Other nodes are not affected
+	other := mustNewDirector(t, "other:1", WithTransport(network), WithCookie("secret"))
+	go func() {
+		actor.release <- true
+		actor.release <- true
+		actor.release <- true
+	}()
+	if _, err := other.Call(pid, (*LimitActor).Wait); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	// Casts count until they are processed
+	waitFor(t, "processed casts", func() bool { return inflight("local:1") == 0 })
+}
+
+func TestRemoteQueueLimit(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network), WithMaxRemoteQueued(2))
+	actor := &LimitActor{release: make(chan bool)}
+	pid := remoteD.StartActor(actor)
+	defer remoteD.Stop(pid)
+
+	a := mustNewDirector(t, "a:1", WithTransport(network))
+	b := mustNewDirector(t, "b:1", WithTransport(network))
+	a.Cast(pid, nil, (*LimitActor).Wait)
+	b.Cast(pid, nil, (*LimitActor).Wait)
+	queued := func() int64 { return atomic.LoadInt64(&actor.remoteQueued) }
+	waitFor(t, "casts", func() bool { return queued() == 2 })
+	if _, err := a.Call(pid, (*LimitActor).Wait); err != ErrMailboxFull {
+		t.Errorf("Expected ErrMailboxFull but got %v\n", err)
+	}
+
+	actor.release <- true
+	actor.release <- true
+	waitFor(t, "processed casts", func() bool { return queued() == 0 })
+
+	// Messages a stopped actor did not process are released as well
+	a.Cast(pid, nil, (*LimitActor).Wait)
+	b.Cast(pid, nil, (*LimitActor).Wait)
+	waitFor(t, "casts", func() bool { return queued() == 2 })
+	remoteD.Stop(pid)
+	close(actor.release)
+	waitFor(t, "released casts", func() bool { return queued() == 0 })
+}
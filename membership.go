@@ -0,0 +1,417 @@
+package cine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemberStatus int
+
+const (
+	MemberJoining MemberStatus = iota
+	MemberUp
+	MemberLeaving
+	MemberDown
+)
+
+func (s MemberStatus) String() string {
+	switch s {
+	case MemberJoining:
+		return "joining"
+	case MemberUp:
+		return "up"
+	case MemberLeaving:
+		return "leaving"
+	case MemberDown:
+		return "down"
+	}
+	return fmt.Sprintf("MemberStatus(%d)", int(s))
+}
+
+// Member is the view of a cluster node. Version is bumped by the node itself on
+// every status change. When two views have the same version the more severe
+// status wins, so a node marked down by others has to bump its version to
+// refute it.
+type Member struct {
+	NodeName string
+	Status   MemberStatus
+	Version  int64
+}
+
+// newer returns true if m supersedes other.
+func (m Member) newer(other Member) bool {
+	if m.Version != other.Version {
+		return m.Version > other.Version
+	}
+	return m.Status > other.Status
+}
+
+// MemberEvent is sent to membership subscribers when the status of a member
+// changes or a new member is discovered.
+type MemberEvent struct {
+	Member Member
+}
+
+type GossipRequest struct {
+	From    string
+	Members []Member
+}
+
+type GossipResponse struct {
+	Err     *DirectorError
+	Members []Member
+}
+
+const kDefaultGossipInterval = time.Second
+const kSubscriptionBuffer = 64
+
+type membership struct {
+	lock        sync.Mutex
+	joined      bool
+	members     map[string]Member
+	seeds       []string
+	monitored   map[string]bool
+	interval    time.Duration
+	timer       Timer
+	rand        *rand.Rand
+	subscribers []chan MemberEvent
+}
+
+// SetGossipInterval sets how often membership is gossiped to a random peer.
+// It must be called before Join.
+func (d *Director) SetGossipInterval(interval time.Duration) {
+	d.membership.lock.Lock()
+	defer d.membership.lock.Unlock()
+	d.membership.interval = interval
+}
+
+// Join makes the director a member of the cluster known by the seed nodes and
+// starts gossiping. A director without seeds (or being its only seed) starts a
+// new cluster.
+func (d *Director) Join(seeds ...string) error {
+	m := &d.membership
+	m.lock.Lock()
+	if m.joined {
+		m.lock.Unlock()
+		return nil
+	}
+	m.joined = true
+	var others []string
+	for _, seed := range seeds {
+		if seed != d.nodeName {
+			others = append(others, seed)
+		}
+	}
+	m.seeds = others
+	self := m.members[d.nodeName]
+	events := m.update(Member{d.nodeName, MemberJoining, self.Version + 1})
+	m.lock.Unlock()
+	d.publishMemberEvents(events)
+
+	// The director is up as soon as it exchanged membership with a seed. If no
+	// seed answers it keeps trying in the gossip loop.
+	var err error
+	if len(others) == 0 {
+		d.setSelfStatus(MemberUp)
+	} else {
+		err = ErrJoinFailed
+		for _, seed := range others {
+			if d.gossipWith(seed) == nil {
+				err = nil
+				break
+			}
+		}
+	}
+
+	m.lock.Lock()
+	m.timer = d.clock.AfterFunc(m.interval, d.gossipLoop)
+	m.lock.Unlock()
+	return err
+}
+
+// Leave announces that the director leaves the cluster and stops gossiping.
+func (d *Director) Leave() {
+	m := &d.membership
+	m.lock.Lock()
+	if !m.joined {
+		m.lock.Unlock()
+		return
+	}
+	m.joined = false
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.lock.Unlock()
+
+	d.setSelfStatus(MemberLeaving)
+	d.gossipToAll()
+	d.setSelfStatus(MemberDown)
+	d.gossipToAll()
+}
+
+// Members returns the known members of the cluster sorted by node name,
+// including the director itself and members that are down.
+func (d *Director) Members() []Member {
+	d.membership.lock.Lock()
+	defer d.membership.lock.Unlock()
+	return d.membership.list()
+}
+
+// SubscribeMembers returns a channel receiving membership events and a
+// function to cancel the subscription. Events are dropped if the subscriber
+// does not keep up.
+func (d *Director) SubscribeMembers() (<-chan MemberEvent, func()) {
+	m := &d.membership
+	c := make(chan MemberEvent, kSubscriptionBuffer)
+	m.lock.Lock()
+	m.subscribers = append(m.subscribers, c)
+	m.lock.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			for i, x := range m.subscribers {
+				if x == c {
+					m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+					break
+				}
+			}
+			close(c)
+		})
+	}
+}
+
+// list must be called within m.lock critical section
+func (m *membership) list() []Member {
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].NodeName < members[j].NodeName
+	})
+	return members
+}
+
+// update stores member if it supersedes the known view and returns the
+// resulting events. Must be called within m.lock critical section.
+func (m *membership) update(member Member) []MemberEvent {
+	cur, ok := m.members[member.NodeName]
+	if ok && !member.newer(cur) {
+		return nil
+	}
+	m.members[member.NodeName] = member
+	if ok && cur.Status == member.Status {
+		return nil
+	}
+	return []MemberEvent{{member}}
+}
+
+// mergeMembers merges a remote view into the local one. Must be called within
+// d.membership.lock critical section.
+func (d *Director) mergeMembers(members []Member) []MemberEvent {
+	m := &d.membership
+	var events []MemberEvent
+	for _, member := range members {
+		if member.NodeName == d.nodeName {
+			self, ok := m.members[d.nodeName]
+			if ok && member.newer(self) && self.Status < MemberLeaving {
+				// Somebody thinks we are gone, refute with a newer version
+				self.Version = member.Version + 1
+				m.members[d.nodeName] = self
+			}
+			continue
+		}
+		events = append(events, m.update(member)...)
+	}
+	return events
+}
+
+func (d *Director) setSelfStatus(status MemberStatus) {
+	m := &d.membership
+	m.lock.Lock()
+	self := m.members[d.nodeName]
+	events := m.update(Member{d.nodeName, status, self.Version + 1})
+	m.lock.Unlock()
+	d.publishMemberEvents(events)
+}
+
+// markMember sets the status of another member as observed by this director.
+func (d *Director) markMember(node string, status MemberStatus) {
+	m := &d.membership
+	m.lock.Lock()
+	cur, ok := m.members[node]
+	if !ok || cur.Status >= status {
+		m.lock.Unlock()
+		return
+	}
+	events := m.update(Member{node, status, cur.Version})
+	m.lock.Unlock()
+	d.publishMemberEvents(events)
+}
+
+func (d *Director) publishMemberEvents(events []MemberEvent) {
+	if len(events) == 0 {
+		return
+	}
+	m := &d.membership
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, event := range events {
+		d.monitorMember(event.Member)
+		for _, c := range m.subscribers {
+			select {
+			case c <- event:
+			default:
+				d.logger.Warnln("Dropping membership event for slow subscriber:", event.Member)
+			}
+		}
+	}
+}
+
+// monitorMember monitors live members with heartbeats so failures mark them
+// down. Must be called within d.membership.lock critical section.
+func (d *Director) monitorMember(member Member) {
+	m := &d.membership
+	if member.NodeName == d.nodeName {
+		return
+	}
+	live := member.Status == MemberJoining || member.Status == MemberUp
+	if live && !m.monitored[member.NodeName] {
+		m.monitored[member.NodeName] = true
+		d.MonitorNode(member.NodeName)
+	} else if !live && m.monitored[member.NodeName] {
+		delete(m.monitored, member.NodeName)
+		d.DemonitorNode(member.NodeName)
+	}
+}
+
+func (d *Director) gossipLoop() {
+	d.gossipRound()
+
+	m := &d.membership
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.joined {
+		m.timer = d.clock.AfterFunc(m.interval, d.gossipLoop)
+	}
+}
+
+// gossipRound exchanges membership with a random live peer, or with a seed if
+// no peer is known. Members that are down are contacted once in a while so two
+// nodes that marked each other down during a partition find each other again.
+// Failures are detected by the node monitor, not by gossip.
+func (d *Director) gossipRound() {
+	m := &d.membership
+	m.lock.Lock()
+	var peers, down []string
+	for _, member := range m.members {
+		if member.NodeName == d.nodeName {
+			continue
+		}
+		if member.Status == MemberDown {
+			down = append(down, member.NodeName)
+		} else {
+			peers = append(peers, member.NodeName)
+		}
+	}
+	if len(peers) == 0 {
+		peers = append(peers, m.seeds...)
+	}
+	if len(down) > 0 && (len(peers) == 0 || m.rand.Intn(4) == 0) {
+		peers = down
+	}
+	if len(peers) == 0 {
+		m.lock.Unlock()
+		return
+	}
+	sort.Strings(peers)
+	peer := peers[m.rand.Intn(len(peers))]
+	m.lock.Unlock()
+
+	if err := d.gossipWith(peer); err != nil {
+		d.logger.Debugln("Gossip with", peer, "failed:", err)
+	}
+}
+
+// gossipToAll pushes the local view to every live peer.
+func (d *Director) gossipToAll() {
+	m := &d.membership
+	m.lock.Lock()
+	var peers []string
+	for _, member := range m.members {
+		if member.NodeName != d.nodeName && member.Status != MemberDown {
+			peers = append(peers, member.NodeName)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, peer := range peers {
+		d.gossipWith(peer)
+	}
+}
+
+// gossipWith does a push-pull exchange of membership with node.
+func (d *Director) gossipWith(node string) *DirectorError {
+	m := &d.membership
+	m.lock.Lock()
+	req := GossipRequest{From: d.nodeName, Members: m.list()}
+	m.lock.Unlock()
+
+	var resp GossipResponse
+	if err := d.remoteCall(node, "HandleGossip", req, &resp); err != nil {
+		return err
+	}
+	if resp.Err != nil {
+		return canonicalError(resp.Err)
+	}
+
+	m.lock.Lock()
+	events := d.mergeMembers(resp.Members)
+	self := m.members[d.nodeName]
+	if m.joined && self.Status == MemberJoining {
+		self.Status = MemberUp
+		self.Version += 1
+		events = append(events, m.update(self)...)
+	}
+	m.lock.Unlock()
+	d.publishMemberEvents(events)
+	return nil
+}
+
+// kGossipMethod is the method name gossip and its entries are authorized
+// with.
+const kGossipMethod = "Gossip"
+
+func (d *DirectorApi) HandleGossip(r GossipRequest, reply *GossipResponse) error {
+	if err := d.authorize(Pid{NodeName: d.director.nodeName}, kGossipMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	if d.peer != "" && r.From != d.peer {
+		d.director.logger.Warnln("Rejecting gossip from", d.peer, "claiming to be", r.From)
+		reply.Err = ErrPermissionDenied
+		return nil
+	}
+	// Entries about other nodes are hearsay, the authorizer decides which
+	// nodes the peer may report about
+	authorizer := d.director.config.authorizer
+	members := make([]Member, 0, len(r.Members))
+	for _, member := range r.Members {
+		if authorizer == nil || authorizer(d.peer, Pid{NodeName: member.NodeName}, kGossipMethod) {
+			members = append(members, member)
+		}
+	}
+	m := &d.director.membership
+	m.lock.Lock()
+	events := d.director.mergeMembers(members)
+	reply.Members = m.list()
+	m.lock.Unlock()
+	d.director.publishMemberEvents(events)
+	return nil
+}
@@ -0,0 +1,122 @@
+package cine
+
+import (
+	"testing"
+	"time"
+)
+
+func newClusterForTest(t *testing.T, n int) []*Director {
+	var directors []*Director
+	for i := 0; i < n; i++ {
+		d := newTestDirector(t)
+		// Gossip is driven by the test
+		d.SetGossipInterval(time.Hour)
+		directors = append(directors, d)
+	}
+	return directors
+}
+
+func memberStatuses(d *Director) map[string]MemberStatus {
+	statuses := make(map[string]MemberStatus)
+	for _, member := range d.Members() {
+		statuses[member.NodeName] = member.Status
+	}
+	return statuses
+}
+
+// gossipUntil runs gossip rounds on every director until cond holds for all
+// of them.
+func gossipUntil(t *testing.T, directors []*Director, cond func(d *Director) bool) {
+	for round := 0; round < 50; round++ {
+		done := true
+		for _, d := range directors {
+			if !cond(d) {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		for _, d := range directors {
+			d.gossipRound()
+		}
+	}
+	t.Fatal("Membership did not converge")
+}
+
+func TestMembership(t *testing.T) {
+	directors := newClusterForTest(t, 3)
+	seed := directors[0]
+	events, cancel := seed.SubscribeMembers()
+	defer cancel()
+
+	if err := seed.Join(); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	for _, d := range directors[1:] {
+		if err := d.Join(seed.nodeName); err != nil {
+			t.Fatalf("Expected no error but got %v\n", err)
+		}
+	}
+
+	allUp := func(d *Director) bool {
+		statuses := memberStatuses(d)
+		if len(statuses) != 3 {
+			return false
+		}
+		for _, status := range statuses {
+			if status != MemberUp {
+				return false
+			}
+		}
+		return true
+	}
+	gossipUntil(t, directors, allUp)
+
+	up := make(map[string]bool)
+	for len(up) < 3 {
+		select {
+		case event := <-events:
+			if event.Member.Status == MemberUp {
+				up[event.Member.NodeName] = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected up events for all members but got %v\n", up)
+		}
+	}
+
+	leaving := directors[2]
+	leaving.Leave()
+	for _, d := range directors[:2] {
+		if status := memberStatuses(d)[leaving.nodeName]; status != MemberDown {
+			t.Errorf("Expected %v to be down on %v but got %v\n", leaving.nodeName, d.nodeName, status)
+		}
+	}
+
+	// Rejoining refutes the old down status
+	leaving.Join(seed.nodeName)
+	gossipUntil(t, directors, allUp)
+}
+
+func TestMembershipJoinFailure(t *testing.T) {
+	d := newClusterForTest(t, 1)[0]
+	if err := d.Join(unusedAddr(t)); err != ErrJoinFailed {
+		t.Errorf("Expected ErrJoinFailed but got %v\n", err)
+	}
+	if status := memberStatuses(d)[d.nodeName]; status != MemberJoining {
+		t.Errorf("Expected to be joining but got %v\n", status)
+	}
+}
+
+func TestMemberNewer(t *testing.T) {
+	up := Member{"a", MemberUp, 2}
+	if !(Member{"a", MemberDown, 2}).newer(up) {
+		t.Error("Expected down to supersede up with the same version")
+	}
+	if (Member{"a", MemberDown, 1}).newer(up) {
+		t.Error("Expected an older version not to supersede")
+	}
+	if !(Member{"a", MemberJoining, 3}).newer(up) {
+		t.Error("Expected a newer version to supersede")
+	}
+}
@@ -9,6 +9,8 @@ type ActorCall struct {
 	Args     []reflect.Value
 	Reply    []reflect.Value
 	Done     chan *ActorCall
+	// span is the span of the caller
+	span SpanContext
 }
 
 func (c ActorCall) ReplyAsInterfaces() []interface{} {
@@ -1,6 +1,9 @@
 package cine
 
-import "container/list"
+import (
+	"container/list"
+	"sync/atomic"
+)
 
 type MessageQueue struct {
 	queue *list.List
@@ -8,6 +11,9 @@ type MessageQueue struct {
 	In    chan *ActorCall
 	Out   chan *ActorCall
 	Stop  chan bool
+
+	// length is updated atomically so Len can be called from any goroutine
+	length int64
 }
 
 func NewMessageQueue(limit int) *MessageQueue {
@@ -23,6 +29,7 @@ func NewMessageQueue(limit int) *MessageQueue {
 
 func (q *MessageQueue) processIn(msg *ActorCall) bool {
 	if msg.Function.IsNil() {
+		atomic.AddInt64(&q.length, -1)
 		return false
 	}
 	q.queue.PushBack(msg)
@@ -60,6 +67,24 @@ func (q *MessageQueue) doOut() bool {
 	return true
 }
 
+// Push sends msg to the queue. Unlike sending to In directly, the message is
+// counted by Len from the moment Push is called.
+func (q *MessageQueue) Push(msg *ActorCall) {
+	atomic.AddInt64(&q.length, 1)
+	q.In <- msg
+}
+
+// Received must be called by the consumer for every message read from Out.
+func (q *MessageQueue) Received() {
+	atomic.AddInt64(&q.length, -1)
+}
+
+// Len returns the number of messages waiting in the queue, including the ones
+// being pushed and the ones read from Out but not yet marked as received.
+func (q *MessageQueue) Len() int {
+	return int(atomic.LoadInt64(&q.length))
+}
+
 func (q *MessageQueue) Run() {
 	defer func() {
 		q.drain()
@@ -84,11 +109,21 @@ func (q *MessageQueue) Run() {
 	}
 }
 
+// drain fails the messages left in the queue and the ones being sent.
 func (q *MessageQueue) drain() {
+	for e := q.queue.Front(); e != nil; e = e.Next() {
+		atomic.AddInt64(&q.length, -1)
+		if r := e.Value.(*ActorCall); r.Done != nil {
+			close(r.Done)
+		}
+	}
+	q.queue.Init()
 	for {
 		select {
 		case r := <-q.In:
-			close(r.Done)
+			if r.Done != nil {
+				close(r.Done)
+			}
 			continue
 		default:
 			return
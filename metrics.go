@@ -0,0 +1,300 @@
+package cine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Labels are the dimensions of a metric, like the method of a message.
+type Labels map[string]string
+
+// Metrics receives the measurements of a director and its actors. Add is
+// used for counters, Set for gauges and Observe for histograms. Registry is
+// the built-in implementation; others can forward to any metrics library.
+type Metrics interface {
+	Add(name string, labels Labels, delta float64)
+	Set(name string, labels Labels, value float64)
+	Observe(name string, labels Labels, value float64)
+}
+
+// Metrics reported by directors and actors.
+const (
+	kMetricActorsStarted    = "cine_actors_started_total"
+	kMetricActorsStopped    = "cine_actors_stopped_total"
+	kMetricActorsPanicked   = "cine_actors_panicked_total"
+	kMetricMailboxDepth     = "cine_mailbox_depth"
+	kMetricMessages         = "cine_messages_processed_total"
+	kMetricHandlerSeconds   = "cine_handler_duration_seconds"
+	kMetricRemoteCalls      = "cine_remote_calls_total"
+	kMetricConnections      = "cine_connections"
+	kMetricConnectionsTotal = "cine_connections_accepted_total"
+	kMetricDialFailures     = "cine_dial_failures_total"
+)
+
+var metricHelp = map[string]string{
+	kMetricActorsStarted:    "Actors started.",
+	kMetricActorsStopped:    "Actors terminated, for any reason.",
+	kMetricActorsPanicked:   "Actors terminated by a panic.",
+	kMetricMailboxDepth:     "Messages waiting in the mailbox when a message is received.",
+	kMetricMessages:         "Messages processed by actors.",
+	kMetricHandlerSeconds:   "Time spent by actors processing a message.",
+	kMetricRemoteCalls:      "Calls to actors of other nodes.",
+	kMetricConnections:      "Open connections from other directors.",
+	kMetricConnectionsTotal: "Connections accepted from other directors.",
+	kMetricDialFailures:     "Failed connections to other directors.",
+}
+
+// WithMetrics reports the metrics of the director and its actors to metrics.
+// nil disables metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(d *Director) {
+		if metrics == nil {
+			metrics = noMetrics{}
+		}
+		d.metrics = metrics
+	}
+}
+
+// noMetrics is used when no Metrics are configured.
+type noMetrics struct{}
+
+func (noMetrics) Add(name string, labels Labels, delta float64)     {}
+func (noMetrics) Set(name string, labels Labels, value float64)     {}
+func (noMetrics) Observe(name string, labels Labels, value float64) {}
+
+// metrics returns the metrics of the director or noMetrics for actors started
+// without a director.
+func (r *Actor) metrics() Metrics {
+	if r.director != nil && r.director.metrics != nil {
+		return r.director.metrics
+	}
+	return noMetrics{}
+}
+
+// functionName returns the name of a method without its package and receiver
+// type, as used in remote requests.
+func functionName(function reflect.Value) string {
+	name := runtime.FuncForPC(function.Pointer()).Name()
+	tokens := strings.Split(name, ".")
+	return tokens[len(tokens)-1]
+}
+
+// callOutcome is the outcome label of a remote call.
+func callOutcome(err *DirectorError) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+// DefaultBuckets are the upper bounds of histograms in seconds.
+var DefaultBuckets = []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5, 10}
+
+// DepthBuckets are the upper bounds of the mailbox depth histogram in
+// messages.
+var DepthBuckets = []float64{0, 1, 2, 5, 10, 50, 100, 500, 1000, 10000}
+
+// metricBuckets are the buckets of histograms not measured in seconds.
+var metricBuckets = map[string][]float64{
+	kMetricMailboxDepth: DepthBuckets,
+}
+
+// Registry keeps metrics in memory and serves them in the Prometheus text
+// format. A name has a single kind, set by its first measurement; measurements
+// of another kind are ignored. Mount it on the director to scrape it:
+//
+//	registry := cine.NewRegistry()
+//	d, err := cine.NewDirector(node, cine.WithMetrics(registry))
+//	d.Handle("/metrics", registry)
+type Registry struct {
+	lock     sync.Mutex
+	buckets  []float64
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	kind    string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labels Labels
+	value  float64
+	// Histograms only, counts are not cumulative
+	counts []uint64
+	count  uint64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:  DefaultBuckets,
+		families: make(map[string]*metricFamily),
+	}
+}
+
+// family returns the family of name, or nil if name has another kind. It must
+// be called with r.lock held.
+func (r *Registry) family(name string, kind string) *metricFamily {
+	family, ok := r.families[name]
+	if !ok {
+		family = &metricFamily{kind: kind, series: make(map[string]*metricSeries)}
+		if kind == "histogram" {
+			family.buckets = r.buckets
+			if buckets, ok := metricBuckets[name]; ok {
+				family.buckets = buckets
+			}
+		}
+		r.families[name] = family
+	}
+	if family.kind != kind {
+		return nil
+	}
+	return family
+}
+
+// series returns the series of name with labels, or nil if name has another
+// kind. It must be called with r.lock held.
+func (r *Registry) series(name string, kind string, labels Labels) *metricSeries {
+	family := r.family(name, kind)
+	if family == nil {
+		return nil
+	}
+	key := formatLabels(labels, "", "")
+	s, ok := family.series[key]
+	if !ok {
+		s = &metricSeries{labels: labels}
+		if kind == "histogram" {
+			s.counts = make([]uint64, len(family.buckets))
+		}
+		family.series[key] = s
+	}
+	return s
+}
+
+func (r *Registry) Add(name string, labels Labels, delta float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if s := r.series(name, "counter", labels); s != nil {
+		s.value += delta
+	}
+}
+
+func (r *Registry) Set(name string, labels Labels, value float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if s := r.series(name, "gauge", labels); s != nil {
+		s.value = value
+	}
+}
+
+func (r *Registry) Observe(name string, labels Labels, value float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	family := r.family(name, "histogram")
+	if family == nil {
+		return
+	}
+	s := r.series(name, "histogram", labels)
+	s.value += value
+	s.count += 1
+	for i, bound := range family.buckets {
+		if value <= bound {
+			s.counts[i] += 1
+			break
+		}
+	}
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var b bytes.Buffer
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		family := r.families[name]
+		if help, ok := metricHelp[name]; ok {
+			fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		}
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, family.kind)
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := family.series[key]
+			if family.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", name, key, formatValue(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, bound := range family.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name,
+					formatLabels(s.labels, "le", formatValue(bound)), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, key, formatValue(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, key, s.count)
+		}
+	}
+	return b.WriteTo(w)
+}
+
+// formatLabels formats labels sorted by name, with an extra label if
+// extraName is not empty.
+func formatLabels(labels Labels, extraName string, extraValue string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(labels[name])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
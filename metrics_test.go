@@ -0,0 +1,125 @@
+package cine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, d *Director) string {
+	resp, err := http.Get("http://" + d.NodeName() + "/metrics")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	return string(body)
+}
+
+func expectMetric(t *testing.T, metrics string, line string) {
+	for _, l := range strings.Split(metrics, "\n") {
+		if l == line {
+			return
+		}
+	}
+	t.Errorf("Expected metric %q but got\n%s", line, metrics)
+}
+
+func TestRegistryFormat(t *testing.T) {
+	r := NewRegistry()
+	r.Add("requests_total", Labels{"path": `a"b`, "code": "200"}, 1)
+	r.Add("requests_total", Labels{"code": "200", "path": `a"b`}, 2)
+	r.Set("temperature", nil, -1.5)
+	r.Observe(kMetricHandlerSeconds, Labels{"method": "Incr"}, 0.002)
+	r.Observe(kMetricHandlerSeconds, Labels{"method": "Incr"}, 20)
+
+	var b bytes.Buffer
+	r.WriteTo(&b)
+	metrics := b.String()
+	expectMetric(t, metrics, `requests_total{code="200",path="a\"b"} 3`)
+	expectMetric(t, metrics, `# TYPE requests_total counter`)
+	expectMetric(t, metrics, `temperature -1.5`)
+	expectMetric(t, metrics, `# TYPE temperature gauge`)
+	expectMetric(t, metrics, `# TYPE cine_handler_duration_seconds histogram`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_bucket{method="Incr",le="0.001"} 0`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_bucket{method="Incr",le="0.005"} 1`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_bucket{method="Incr",le="10"} 1`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_bucket{method="Incr",le="+Inf"} 2`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_sum{method="Incr"} 20.002`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_count{method="Incr"} 2`)
+}
+
+func TestDirectorMetrics(t *testing.T) {
+	registry := NewRegistry()
+	remoteD := mustNewDirector(t, "127.0.0.1:0", WithMetrics(registry))
+	remoteD.Handle("/metrics", registry)
+	pid := remoteD.StartActor(&Counter{})
+	remoteD.Call(pid, (*Counter).Incr)
+	worker := remoteD.StartActor(&Worker{Actor{}, "worker", nil})
+	remoteD.Cast(worker, nil, (*Worker).Crash)
+
+	localRegistry := NewRegistry()
+	d := mustNewDirector(t, "127.0.0.1:0", WithMetrics(localRegistry))
+	d.Call(pid, (*Counter).Incr)
+	d.Call(Pid{NodeName: remoteD.NodeName(), ActorId: 42, Creation: pid.Creation}, (*Counter).Incr)
+	d.Call(Pid{NodeName: unusedAddr(t)}, (*Counter).Incr)
+	remoteD.Stop(pid)
+	waitFor(t, "stop", func() bool {
+		return strings.Contains(scrape(t, remoteD), "cine_actors_stopped_total 2\n")
+	})
+
+	metrics := scrape(t, remoteD)
+	expectMetric(t, metrics, "cine_actors_started_total 2")
+	expectMetric(t, metrics, "cine_actors_panicked_total 1")
+	expectMetric(t, metrics, `cine_messages_processed_total{method="Incr"} 2`)
+	expectMetric(t, metrics, `cine_handler_duration_seconds_count{method="Incr"} 2`)
+	expectMetric(t, metrics, "cine_mailbox_depth_count 3")
+	// Scrapes are connections as well
+	if !strings.Contains(metrics, "cine_connections_accepted_total ") {
+		t.Errorf("Expected connections to be counted but got\n%s", metrics)
+	}
+
+	var b bytes.Buffer
+	localRegistry.WriteTo(&b)
+	metrics = b.String()
+	expectMetric(t, metrics, `cine_remote_calls_total{node="`+remoteD.NodeName()+`",outcome="ok"} 1`)
+	expectMetric(t, metrics, `cine_remote_calls_total{node="`+remoteD.NodeName()+`",outcome="Actor not found"} 1`)
+	if !strings.Contains(metrics, "cine_dial_failures_total{node=") {
+		t.Errorf("Expected a dial failure but got\n%s", metrics)
+	}
+}
+
+func TestRegistryKinds(t *testing.T) {
+	r := NewRegistry()
+	r.Add("requests_total", nil, 1)
+	// Measurements of another kind are ignored
+	r.Set("requests_total", nil, 5)
+	r.Observe("requests_total", nil, 5)
+	r.Observe(kMetricMailboxDepth, nil, 0)
+	r.Observe(kMetricMailboxDepth, nil, 3)
+
+	var b bytes.Buffer
+	r.WriteTo(&b)
+	metrics := b.String()
+	expectMetric(t, metrics, `requests_total 1`)
+	expectMetric(t, metrics, `# TYPE requests_total counter`)
+	// Mailbox depth is counted in messages
+	expectMetric(t, metrics, `cine_mailbox_depth_bucket{le="0"} 1`)
+	expectMetric(t, metrics, `cine_mailbox_depth_bucket{le="2"} 1`)
+	expectMetric(t, metrics, `cine_mailbox_depth_bucket{le="5"} 2`)
+	expectMetric(t, metrics, `cine_mailbox_depth_bucket{le="10000"} 2`)
+}
+
+func TestWithNilMetrics(t *testing.T) {
+	d := mustNewDirector(t, "127.0.0.1:0", WithMetrics(nil))
+	pid := d.StartActor(&Counter{})
+	defer d.Stop(pid)
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
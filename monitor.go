@@ -0,0 +1,262 @@
+package cine
+
+import "sync"
+
+// DownHandler is implemented by actors that monitor other actors. ActorDown is
+// cast to the watcher when the monitored actor terminates.
+type DownHandler interface {
+	ActorDown(pid Pid, reason string)
+}
+
+// supervision holds monitors and links of the local actors of a director.
+// monitors maps a local actor to its watchers and links maps a local actor to
+// the actors it is linked with, which may live on other nodes.
+type supervision struct {
+	lock     sync.Mutex
+	monitors map[Pid][]Pid
+	links    map[Pid][]Pid
+}
+
+type MonitorRequest struct {
+	Watcher Pid
+	Target  Pid
+}
+
+type LinkRequest struct {
+	Pid Pid
+	To  Pid
+}
+
+// isNormalExit returns true for termination reasons that do not propagate to
+// linked actors.
+func isNormalExit(reason error) bool {
+	return reason == ErrActorStop || reason == ErrActorIdle || reason == ErrShutdown
+}
+
+// Monitor makes watcher receive ActorDown when target terminates. watcher must
+// implement DownHandler. If target does not exist, ActorDown is sent right
+// away with the ErrActorNotFound reason.
+func (d *Director) Monitor(watcher Pid, target Pid) *DirectorError {
+	if target.NodeName != d.nodeName {
+		var resp RemoteResponse
+		if err := d.remoteCall(target.NodeName, "HandleMonitor", MonitorRequest{watcher, target}, &resp); err != nil {
+			return err
+		}
+		if resp.Err != nil {
+			return canonicalError(resp.Err)
+		}
+		return nil
+	}
+
+	// The target is removed before notifyExit takes the supervision lock, so
+	// checking it under the lock guarantees the monitor is notified
+	d.supervision.lock.Lock()
+	if _, err := d.localActorFromPid(target); err != nil {
+		d.supervision.lock.Unlock()
+		d.sendDown(watcher, target, ErrActorNotFound)
+		return nil
+	}
+	d.supervision.monitors[target] = append(d.supervision.monitors[target], watcher)
+	d.supervision.lock.Unlock()
+	return nil
+}
+
+// Demonitor removes a monitor set up with Monitor.
+func (d *Director) Demonitor(watcher Pid, target Pid) *DirectorError {
+	if target.NodeName != d.nodeName {
+		var resp RemoteResponse
+		if err := d.remoteCall(target.NodeName, "HandleDemonitor", MonitorRequest{watcher, target}, &resp); err != nil {
+			return err
+		}
+		if resp.Err != nil {
+			return canonicalError(resp.Err)
+		}
+		return nil
+	}
+
+	d.supervision.lock.Lock()
+	defer d.supervision.lock.Unlock()
+	d.supervision.monitors[target] = removePid(d.supervision.monitors[target], watcher)
+	if len(d.supervision.monitors[target]) == 0 {
+		delete(d.supervision.monitors, target)
+	}
+	return nil
+}
+
+// Link links two actors. When one of them terminates abnormally the other is
+// stopped with ErrLinkedActorDied.
+func (d *Director) Link(a Pid, b Pid) *DirectorError {
+	if err := d.addLink(a, b); err != nil {
+		return err
+	}
+	return d.addLink(b, a)
+}
+
+// addLink records the link from pid to to on the node of pid.
+func (d *Director) addLink(pid Pid, to Pid) *DirectorError {
+	if pid.NodeName != d.nodeName {
+		var resp RemoteResponse
+		if err := d.remoteCall(pid.NodeName, "HandleLink", LinkRequest{pid, to}, &resp); err != nil {
+			return err
+		}
+		if resp.Err == nil {
+			return nil
+		}
+		if err := canonicalError(resp.Err); err != ErrActorNotFound {
+			return err
+		}
+		// The other node does not stop actors on our behalf
+		d.exitActor(to)
+		return nil
+	}
+	if !d.linkLocal(pid, to) {
+		// Linking a dead actor kills the other end
+		d.exitActor(to)
+	}
+	return nil
+}
+
+// linkLocal records the link from the local actor pid to to. It returns false
+// if pid is not running.
+func (d *Director) linkLocal(pid Pid, to Pid) bool {
+	// Checked under the lock like in Monitor
+	d.supervision.lock.Lock()
+	defer d.supervision.lock.Unlock()
+	if _, err := d.localActorFromPid(pid); err != nil {
+		return false
+	}
+	d.supervision.links[pid] = append(d.supervision.links[pid], to)
+	return true
+}
+
+// notifyExit sends ActorDown to the watchers of a terminated local actor and
+// stops the linked actors if the actor terminated abnormally.
+func (d *Director) notifyExit(pid Pid, reason error) {
+	d.supervision.lock.Lock()
+	watchers := d.supervision.monitors[pid]
+	links := d.supervision.links[pid]
+	delete(d.supervision.monitors, pid)
+	delete(d.supervision.links, pid)
+	d.supervision.lock.Unlock()
+
+	for _, watcher := range watchers {
+		d.sendDown(watcher, pid, reason)
+	}
+	for _, linked := range links {
+		if isNormalExit(reason) {
+			d.unlinkLocal(linked, pid)
+		} else {
+			d.exitActor(linked)
+		}
+	}
+}
+
+func (d *Director) sendDown(watcher Pid, pid Pid, reason error) {
+	if watcher.NodeName == d.nodeName {
+		actor, err := d.localActorFromPid(watcher)
+		if err != nil {
+			return
+		}
+		if _, ok := actor.receiver.Interface().(DownHandler); !ok {
+			return
+		}
+	}
+	d.Cast(watcher, nil, DownHandler.ActorDown, pid, reason.Error())
+}
+
+// unlinkLocal drops a link of a local actor. Links of remote actors to a
+// normally terminated actor are dropped when the remote actor terminates.
+func (d *Director) unlinkLocal(pid Pid, to Pid) {
+	if pid.NodeName != d.nodeName {
+		return
+	}
+	d.supervision.lock.Lock()
+	defer d.supervision.lock.Unlock()
+	d.supervision.links[pid] = removePid(d.supervision.links[pid], to)
+	if len(d.supervision.links[pid]) == 0 {
+		delete(d.supervision.links, pid)
+	}
+}
+
+// exitActor stops a linked actor with ErrLinkedActorDied.
+func (d *Director) exitActor(pid Pid) {
+	if pid.NodeName != d.nodeName {
+		var resp RemoteResponse
+		d.remoteCall(pid.NodeName, "HandleRemoteExit", RemoteRequest{Pid: pid}, &resp)
+		return
+	}
+	actor, err := d.localActorFromPid(pid)
+	if err != nil {
+		return
+	}
+	actor.stopWithReason(ErrLinkedActorDied)
+}
+
+func removePid(pids []Pid, pid Pid) []Pid {
+	for i, x := range pids {
+		if x == pid {
+			return append(pids[:i], pids[i+1:]...)
+		}
+	}
+	return pids
+}
+
+// Method names remote supervision requests are authorized with. Exits of
+// linked actors are authorized like stops.
+const (
+	kMonitorMethod   = "Monitor"
+	kDemonitorMethod = "Demonitor"
+	kLinkMethod      = "Link"
+)
+
+func (d *DirectorApi) HandleMonitor(r MonitorRequest, reply *RemoteResponse) error {
+	if err := d.authorize(r.Target, kMonitorMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	reply.Err = d.director.Monitor(r.Watcher, r.Target)
+	return nil
+}
+
+func (d *DirectorApi) HandleDemonitor(r MonitorRequest, reply *RemoteResponse) error {
+	if err := d.authorize(r.Target, kDemonitorMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	reply.Err = d.director.Demonitor(r.Watcher, r.Target)
+	return nil
+}
+
+func (d *DirectorApi) HandleLink(r LinkRequest, reply *RemoteResponse) error {
+	if err := d.authorize(r.Pid, kLinkMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	// A link stops a local To when Pid dies, so it needs the right to stop it
+	if err := d.authorizeStop(r.To); err != nil {
+		reply.Err = err
+		return nil
+	}
+	if r.Pid.NodeName != d.director.nodeName || !d.director.linkLocal(r.Pid, r.To) {
+		reply.Err = ErrActorNotFound
+	}
+	return nil
+}
+
+// authorizeStop checks that the peer may stop pid if it is a local actor.
+func (d *DirectorApi) authorizeStop(pid Pid) *DirectorError {
+	if pid.NodeName != d.director.nodeName {
+		// Stopped through HandleRemoteExit, authorized by its node
+		return nil
+	}
+	return d.authorize(pid, kStopMethod)
+}
+
+func (d *DirectorApi) HandleRemoteExit(r RemoteRequest, reply *RemoteResponse) error {
+	if err := d.authorize(r.Pid, kStopMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	d.director.exitActor(r.Pid)
+	return nil
+}
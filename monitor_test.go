@@ -0,0 +1,153 @@
+package cine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type Worker struct {
+	Actor
+	name    string
+	reasons chan error
+}
+
+func (w *Worker) Name() string {
+	return w.name
+}
+
+func (w *Worker) Crash() {
+	panic("crash")
+}
+
+func (w *Worker) ExposedMethods() []string {
+	return []string{"Name", "Crash"}
+}
+
+func (w *Worker) Terminate(errReason error) {
+	if w.reasons != nil {
+		w.reasons <- errReason
+	}
+}
+
+type Watcher struct {
+	Actor
+	downs chan string
+}
+
+func (w *Watcher) ActorDown(pid Pid, reason string) {
+	w.downs <- fmt.Sprintf("%v %s", pid, reason)
+}
+
+func (w *Watcher) Terminate(errReason error) {
+}
+
+func expectString(t *testing.T, c chan string, expected string) {
+	select {
+	case s := <-c:
+		if s != expected {
+			t.Errorf("Expected %q but got %q\n", expected, s)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for %q\n", expected)
+	}
+}
+
+func expectReason(t *testing.T, c chan error, expected error) {
+	select {
+	case reason := <-c:
+		if reason != expected {
+			t.Errorf("Expected %v but got %v\n", expected, reason)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for %v\n", expected)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	watcher := &Watcher{Actor{}, make(chan string, 10)}
+	watcherPid := d.StartActor(watcher)
+	defer d.Stop(watcherPid)
+
+	local := d.StartActor(&Worker{Actor{}, "local", nil})
+	remote := remoteD.StartActor(&Worker{Actor{}, "remote", nil})
+	if err := d.Monitor(watcherPid, local); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if err := d.Monitor(watcherPid, remote); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+
+	d.Stop(local)
+	expectString(t, watcher.downs, fmt.Sprintf("%v Actor stop", local))
+	d.Cast(remote, nil, (*Worker).Crash)
+	expectString(t, watcher.downs, fmt.Sprintf("%v Actor panic: crash", remote))
+
+	// Monitoring a dead actor reports it right away
+	d.Monitor(watcherPid, local)
+	expectString(t, watcher.downs, fmt.Sprintf("%v Actor not found", local))
+
+	other := d.StartActor(&Worker{Actor{}, "other", nil})
+	d.Monitor(watcherPid, other)
+	d.Demonitor(watcherPid, other)
+	d.Stop(other)
+	select {
+	case s := <-watcher.downs:
+		t.Errorf("Unexpected ActorDown after Demonitor: %s\n", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLink(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+
+	a := &Worker{Actor{}, "a", make(chan error, 1)}
+	b := &Worker{Actor{}, "b", make(chan error, 1)}
+	aPid := d.StartActor(a)
+	bPid := remoteD.StartActor(b)
+	if err := d.Link(aPid, bPid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	d.Cast(bPid, nil, (*Worker).Crash)
+	expectReason(t, a.reasons, ErrLinkedActorDied)
+
+	// Normal exits do not propagate
+	c := &Worker{Actor{}, "c", make(chan error, 1)}
+	e := &Worker{Actor{}, "e", make(chan error, 1)}
+	cPid := d.StartActor(c)
+	ePid := remoteD.StartActor(e)
+	defer remoteD.Stop(ePid)
+	d.Link(cPid, ePid)
+	d.Stop(cPid)
+	expectReason(t, c.reasons, ErrActorStop)
+	if _, err := d.Call(ePid, (*Worker).Name); err != nil {
+		t.Errorf("Expected linked actor to survive a normal exit but got %v\n", err)
+	}
+}
+
+func TestMonitorExitingActor(t *testing.T) {
+	d := newTestDirector(t)
+	watcher := &Watcher{Actor{}, make(chan string, 10)}
+	watcherPid := d.StartActor(watcher)
+	defer d.Stop(watcherPid)
+
+	// Whichever of the stop and the monitor comes first, one ActorDown is sent
+	for i := 0; i < 50; i++ {
+		pid := d.StartActor(&Worker{Actor{}, "w", nil})
+		go d.Stop(pid)
+		d.Monitor(watcherPid, pid)
+		select {
+		case <-watcher.downs:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected ActorDown for %v\n", pid)
+		}
+	}
+	d.supervision.lock.Lock()
+	defer d.supervision.lock.Unlock()
+	if len(d.supervision.monitors) != 0 {
+		t.Errorf("Expected no monitor left but got %v\n", d.supervision.monitors)
+	}
+}
@@ -0,0 +1,332 @@
+package cine
+
+import (
+	"fmt"
+	"sync"
+)
+
+type NodeEventType int
+
+const (
+	NodeUp NodeEventType = iota
+	NodeDown
+)
+
+func (t NodeEventType) String() string {
+	switch t {
+	case NodeUp:
+		return "up"
+	case NodeDown:
+		return "down"
+	}
+	return fmt.Sprintf("NodeEventType(%d)", int(t))
+}
+
+// NodeEvent is sent to node subscribers when a monitored node goes up or down.
+type NodeEvent struct {
+	Node   string
+	Type   NodeEventType
+	Reason string
+}
+
+type PingRequest struct {
+	From string
+}
+
+type PingResponse struct {
+}
+
+type nodeState int
+
+const (
+	nodeUnknown nodeState = iota
+	nodeIsUp
+	nodeIsDown
+)
+
+// heartbeatTransport sends a heartbeat to a node and waits for the answer.
+// The director sends heartbeats over RPC; tests replace it to delay or drop
+// heartbeats.
+type heartbeatTransport interface {
+	sendHeartbeat(node string) *DirectorError
+}
+
+type monitoredNode struct {
+	name     string
+	refs     int
+	state    nodeState
+	detector *PhiAccrualDetector
+	pinging  bool
+	timer    Timer
+	// client is the connection heartbeats are sent on, dialed by the first
+	// heartbeat and dropped when a heartbeat fails
+	client rpcClient
+}
+
+// nodeMonitor sends heartbeats to monitored nodes and reports node events.
+type nodeMonitor struct {
+	lock        sync.Mutex
+	nodes       map[string]*monitoredNode
+	config      FailureDetectorConfig
+	transport   heartbeatTransport
+	subscribers []chan NodeEvent
+}
+
+// SetFailureDetector configures the heartbeats to monitored nodes and the phi
+// threshold above which a node is considered down. It must be called before
+// any node is monitored. Zero or invalid fields are replaced by the ones of
+// DefaultFailureDetectorConfig.
+func (d *Director) SetFailureDetector(config FailureDetectorConfig) {
+	d.nodeMonitor.lock.Lock()
+	defer d.nodeMonitor.lock.Unlock()
+	d.nodeMonitor.config = config.withDefaults()
+}
+
+// MonitorNode starts sending heartbeats to node. Subscribers get a NodeUp event
+// once the node answers and a NodeDown event when the node cannot be reached,
+// the connection to it is lost or it is suspected by the failure detector.
+// Calls nest; every MonitorNode must be matched by a DemonitorNode.
+func (d *Director) MonitorNode(node string) {
+	if node == d.nodeName {
+		return
+	}
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+
+	if n, ok := nm.nodes[node]; ok {
+		n.refs += 1
+		return
+	}
+	// Monitoring starts as if a heartbeat was received so a node that never
+	// answers is suspected as well.
+	n := &monitoredNode{
+		name:     node,
+		refs:     1,
+		detector: NewPhiAccrualDetector(nm.config, d.clock.Now()),
+		pinging:  true,
+	}
+	nm.nodes[node] = n
+	go d.ping(n)
+	n.timer = d.clock.AfterFunc(nm.config.HeartbeatInterval, func() { d.heartbeat(n) })
+}
+
+// Suspicion returns the phi value of the failure detector for a monitored
+// node, 0 if the node is not monitored.
+func (d *Director) Suspicion(node string) float64 {
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+	n, ok := nm.nodes[node]
+	if !ok {
+		return 0
+	}
+	return n.detector.Phi(d.clock.Now())
+}
+
+// DemonitorNode stops monitoring node once every MonitorNode call is matched.
+func (d *Director) DemonitorNode(node string) {
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+
+	n, ok := nm.nodes[node]
+	if !ok {
+		return
+	}
+	n.refs -= 1
+	if n.refs == 0 {
+		n.timer.Stop()
+		delete(nm.nodes, node)
+		n.closeClient()
+	}
+}
+
+// closeClient closes the heartbeat connection. Must be called within nm.lock
+// critical section.
+func (n *monitoredNode) closeClient() {
+	if n.client != nil {
+		n.client.Close()
+		n.client = nil
+	}
+}
+
+// SubscribeNodes returns a channel receiving node events and a function to
+// cancel the subscription. Events are dropped if the subscriber does not keep
+// up.
+func (d *Director) SubscribeNodes() (<-chan NodeEvent, func()) {
+	nm := &d.nodeMonitor
+	c := make(chan NodeEvent, kSubscriptionBuffer)
+	nm.lock.Lock()
+	nm.subscribers = append(nm.subscribers, c)
+	nm.lock.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			nm.lock.Lock()
+			defer nm.lock.Unlock()
+			for i, x := range nm.subscribers {
+				if x == c {
+					nm.subscribers = append(nm.subscribers[:i], nm.subscribers[i+1:]...)
+					break
+				}
+			}
+			close(c)
+		})
+	}
+}
+
+// heartbeat is called every heartbeat interval for a monitored node. The ping
+// runs in its own goroutine so a hanging node is detected by the failure
+// detector.
+func (d *Director) heartbeat(n *monitoredNode) {
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	if nm.nodes[n.name] != n {
+		// Demonitored
+		nm.lock.Unlock()
+		return
+	}
+	if !n.pinging {
+		n.pinging = true
+		go d.ping(n)
+	}
+	var event *NodeEvent
+	if phi := n.detector.Phi(d.clock.Now()); n.state != nodeIsDown && phi > nm.config.Threshold {
+		event = nm.setState(n, nodeIsDown, fmt.Sprintf("suspected (phi %.1f)", phi))
+	}
+	n.timer = d.clock.AfterFunc(nm.config.HeartbeatInterval, func() { d.heartbeat(n) })
+	nm.lock.Unlock()
+
+	d.publishNodeEvent(event)
+}
+
+// ping sends a heartbeat to a monitored node. A node that refuses connections
+// is down right away; other failures are left to the failure detector.
+func (d *Director) ping(n *monitoredNode) {
+	nm := &d.nodeMonitor
+	err := nm.transport.sendHeartbeat(n.name)
+
+	nm.lock.Lock()
+	n.pinging = false
+	var event *NodeEvent
+	if nm.nodes[n.name] != n {
+		// Demonitored while pinging
+	} else if err == ErrNodeUnreachable {
+		event = nm.setState(n, nodeIsDown, err.Error())
+	} else if err != nil {
+		d.logger.Debugln("Heartbeat to", n.name, "failed:", err)
+	} else {
+		now := d.clock.Now()
+		if n.state == nodeIsDown {
+			// The intervals seen while the node was down say nothing about it
+			n.detector = NewPhiAccrualDetector(nm.config, now)
+		} else {
+			n.detector.Heartbeat(now)
+		}
+		event = nm.setState(n, nodeIsUp, "heartbeat")
+	}
+	nm.lock.Unlock()
+
+	d.publishNodeEvent(event)
+}
+
+// connectionLost is called when a connection to node is closed unexpectedly.
+func (d *Director) connectionLost(node string, reason string) {
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	var event *NodeEvent
+	if n, ok := nm.nodes[node]; ok {
+		event = nm.setState(n, nodeIsDown, reason)
+	}
+	nm.lock.Unlock()
+
+	d.publishNodeEvent(event)
+}
+
+// setState returns the event to publish if the state changed. Must be called
+// within nm.lock critical section.
+func (nm *nodeMonitor) setState(n *monitoredNode, state nodeState, reason string) *NodeEvent {
+	if n.state == state {
+		return nil
+	}
+	n.state = state
+	if state == nodeIsUp {
+		return &NodeEvent{n.name, NodeUp, reason}
+	}
+	return &NodeEvent{n.name, NodeDown, reason}
+}
+
+func (d *Director) publishNodeEvent(event *NodeEvent) {
+	if event == nil {
+		return
+	}
+	if event.Type == NodeDown {
+		d.logger.Warnln("Node", event.Node, "is down:", event.Reason)
+		d.markMember(event.Node, MemberDown)
+	}
+
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+	for _, c := range nm.subscribers {
+		select {
+		case c <- *event:
+		default:
+			d.logger.Warnln("Dropping node event for slow subscriber:", event.Node, event.Type)
+		}
+	}
+}
+
+// sendHeartbeat sends a heartbeat on the connection kept for the monitored
+// node. The connection is dialed again after a failure.
+func (d *Director) sendHeartbeat(node string) *DirectorError {
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	n, ok := nm.nodes[node]
+	var client rpcClient
+	if ok {
+		client = n.client
+	}
+	nm.lock.Unlock()
+	if !ok {
+		// Demonitored, the answer is ignored anyway
+		return nil
+	}
+
+	if client == nil {
+		// Heartbeats of a node are sent one at a time, nobody else dials
+		c, err := d.dial(node)
+		if err != nil {
+			d.metrics.Add(kMetricDialFailures, Labels{"node": node}, 1)
+			d.logger.Debugln("Cannot connect to", node, err)
+			return connectError(err, ErrNodeUnreachable)
+		}
+		client = d.wrapClient(node, c)
+		nm.lock.Lock()
+		if nm.nodes[node] == n {
+			n.client = client
+		} else {
+			defer client.Close()
+		}
+		nm.lock.Unlock()
+	}
+
+	rActor := &RemoteActor{pid: Pid{NodeName: node}, client: client, director: d}
+	var resp PingResponse
+	call := client.Go("DirectorApi.HandlePing", PingRequest{d.nodeName}, &resp, nil)
+	err := rActor.handleCall(call)
+	if err != nil {
+		nm.lock.Lock()
+		if n.client == client {
+			n.closeClient()
+		}
+		nm.lock.Unlock()
+	}
+	return err
+}
+
+func (d *DirectorApi) HandlePing(r PingRequest, reply *PingResponse) error {
+	return nil
+}
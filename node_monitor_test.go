@@ -0,0 +1,143 @@
+package cine
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func expectNodeEvent(t *testing.T, events <-chan NodeEvent, node string, typ NodeEventType) NodeEvent {
+	select {
+	case event := <-events:
+		if event.Node != node || event.Type != typ {
+			t.Errorf("Expected %v %v but got %v\n", node, typ, event)
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %v %v\n", node, typ)
+	}
+	return NodeEvent{}
+}
+
+func TestMonitorNode(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	events, cancel := d.SubscribeNodes()
+	defer cancel()
+
+	d.MonitorNode(remoteD.nodeName)
+	defer d.DemonitorNode(remoteD.nodeName)
+	expectNodeEvent(t, events, remoteD.nodeName, NodeUp)
+
+	deadNode := unusedAddr(t)
+	d.MonitorNode(deadNode)
+	defer d.DemonitorNode(deadNode)
+	event := expectNodeEvent(t, events, deadNode, NodeDown)
+	if event.Reason != ErrNodeUnreachable.Error() {
+		t.Errorf("Expected %q but got %q\n", ErrNodeUnreachable, event.Reason)
+	}
+}
+
+func TestMonitorNodeSuspected(t *testing.T) {
+	// A peer that accepts connections but never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	d := newTestDirector(t)
+	clock := NewFakeClock(time.Now())
+	d.SetClock(clock)
+	events, cancel := d.SubscribeNodes()
+	defer cancel()
+
+	hanging := l.Addr().String()
+	d.MonitorNode(hanging)
+	defer d.DemonitorNode(hanging)
+	// The default acceptable pause of 3s on top of the 1s interval tolerates
+	// 5s without answer
+	for i := 0; i < 5; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event before suspicion %v\n", event)
+	default:
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	event := expectNodeEvent(t, events, hanging, NodeDown)
+	if !strings.HasPrefix(event.Reason, "suspected") {
+		t.Errorf("Expected suspected but got %q\n", event.Reason)
+	}
+}
+
+func TestMembersMonitored(t *testing.T) {
+	directors := newClusterForTest(t, 2)
+	directors[0].Join()
+	directors[1].Join(directors[0].nodeName)
+
+	for _, d := range directors {
+		d.nodeMonitor.lock.Lock()
+		n := len(d.nodeMonitor.nodes)
+		d.nodeMonitor.lock.Unlock()
+		if n != 1 {
+			t.Errorf("Expected %v to monitor the other member but monitors %d nodes\n", d.nodeName, n)
+		}
+	}
+
+	// A node down event marks the member down
+	d := directors[0]
+	d.connectionLost(directors[1].nodeName, "connection reset")
+	if status := memberStatuses(d)[directors[1].nodeName]; status != MemberDown {
+		t.Errorf("Expected member down but got %v\n", status)
+	}
+}
+
+func openConns(d *Director) int {
+	d.listener.lock.Lock()
+	defer d.listener.lock.Unlock()
+	return len(d.listener.conns)
+}
+
+func TestMonitorNodeConnection(t *testing.T) {
+	d := newTestDirector(t)
+	// The connection outlives the read timeout
+	remoteD := mustNewDirector(t, "127.0.0.1:0", WithReadTimeout(30*time.Millisecond))
+	config := DefaultFailureDetectorConfig
+	config.HeartbeatInterval = 10 * time.Millisecond
+	d.SetFailureDetector(config)
+	events, cancel := d.SubscribeNodes()
+	defer cancel()
+
+	// Heartbeats reuse one connection
+	d.MonitorNode(remoteD.nodeName)
+	expectNodeEvent(t, events, remoteD.nodeName, NodeUp)
+	time.Sleep(100 * time.Millisecond)
+	if n := openConns(remoteD); n != 1 {
+		t.Errorf("Expected one connection but got %d\n", n)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event %v\n", event)
+	default:
+	}
+
+	d.DemonitorNode(remoteD.nodeName)
+	waitFor(t, "connection closed", func() bool {
+		return openConns(remoteD) == 0
+	})
+}
@@ -0,0 +1,165 @@
+package cine
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Logger is the logger used by a director. *logrus.Logger and *logrus.Entry
+// implement it.
+type Logger interface {
+	Debugln(args ...interface{})
+	Infoln(args ...interface{})
+	Warnln(args ...interface{})
+	Errorln(args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+const (
+	kDefaultReadTimeout    = 10 * time.Second
+	kDefaultWriteTimeout   = 10 * time.Second
+	kDefaultDialTimeout    = 10 * time.Second
+	kDefaultCallTimeout    = 30 * time.Second
+	kDefaultMaxHeaderBytes = 1 << 20
+	kDefaultMaxMessageSize = 16 << 20
+)
+
+type directorConfig struct {
+	transport       Transport
+	listener        net.Listener
+	listenAddr      string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	dialTimeout     time.Duration
+	callTimeout     time.Duration
+	maxMessageSize  int64
+	codec           Codec
+	tlsServer       *tls.Config
+	tlsClient       *tls.Config
+	cookies         *cookies
+	authorizer      Authorizer
+	capabilities    bool
+	maxArgs         int
+	maxArgDepth     int
+	maxPeerRequests int
+	maxRemoteQueued int
+	debugToken      string
+}
+
+func defaultDirectorConfig() directorConfig {
+	return directorConfig{
+		transport:       TCPTransport{},
+		readTimeout:     kDefaultReadTimeout,
+		writeTimeout:    kDefaultWriteTimeout,
+		dialTimeout:     kDefaultDialTimeout,
+		callTimeout:     kDefaultCallTimeout,
+		maxMessageSize:  kDefaultMaxMessageSize,
+		codec:           GobCodec{},
+		maxArgs:         kDefaultMaxArgs,
+		maxArgDepth:     kDefaultMaxArgDepth,
+		maxPeerRequests: kDefaultMaxPeerRequests,
+		maxRemoteQueued: kDefaultMaxRemoteQueued,
+	}
+}
+
+// Option configures a director created by NewDirector.
+type Option func(d *Director)
+
+// WithTransport sets the transport used to listen and to connect to other
+// directors.
+func WithTransport(transport Transport) Option {
+	return func(d *Director) {
+		d.config.transport = transport
+	}
+}
+
+// WithListener makes the director serve on l instead of listening itself. The
+// director closes l on shutdown.
+func WithListener(l net.Listener) Option {
+	return func(d *Director) {
+		d.config.listener = l
+	}
+}
+
+// WithListenAddr sets the address to listen on when it differs from the node
+// name advertised to other directors, e.g. behind NAT or in a container. By
+// default the director listens on all interfaces at the port of the node name
+// with TCPTransport and on the node name with other transports.
+func WithListenAddr(addr string) Option {
+	return func(d *Director) {
+		d.config.listenAddr = addr
+	}
+}
+
+// WithReadTimeout sets the time allowed to read the connection handshake and
+// each request from other directors. Connections waiting for the next request
+// are kept open.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(d *Director) {
+		d.config.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the time allowed to write the connection handshake and
+// each response to other directors.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(d *Director) {
+		d.config.writeTimeout = timeout
+	}
+}
+
+// WithDialTimeout sets the timeout to connect to other directors.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(d *Director) {
+		d.config.dialTimeout = timeout
+	}
+}
+
+// WithCallTimeout sets how long a call to another director waits for the
+// reply before failing with ErrCallTimeout. Calls with a context deadline wait
+// for the deadline instead. Zero means no timeout.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(d *Director) {
+		d.config.callTimeout = timeout
+	}
+}
+
+// WithMaxMessageSize sets the maximum size in bytes of a request from another
+// director. The connection is closed when a request is larger. It is the only
+// bound on the memory used to decode a request, as the limits on arguments are
+// checked once it is decoded. The default is 16 MiB and zero means no limit.
+func WithMaxMessageSize(size int64) Option {
+	return func(d *Director) {
+		d.config.maxMessageSize = size
+	}
+}
+
+// WithCodec sets the codec of connections between directors. All directors of
+// a cluster must use the same codec.
+func WithCodec(codec Codec) Option {
+	return func(d *Director) {
+		d.config.codec = codec
+	}
+}
+
+// WithLogger sets the logger of the director and its actors.
+func WithLogger(logger Logger) Option {
+	return func(d *Director) {
+		d.logger = logger
+	}
+}
+
+// WithClock sets the clock of the director and its actors.
+func WithClock(clock Clock) Option {
+	return func(d *Director) {
+		d.clock = clock
+	}
+}
+
+// defaultLogger is used by actors started without a director.
+func defaultLogger() Logger {
+	return log.StandardLogger()
+}
@@ -0,0 +1,111 @@
+package cine
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	lock  sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) record(line string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, line)
+}
+
+func (l *recordingLogger) Debugln(args ...interface{}) { l.record(fmt.Sprintln(args...)) }
+func (l *recordingLogger) Infoln(args ...interface{})  { l.record(fmt.Sprintln(args...)) }
+func (l *recordingLogger) Warnln(args ...interface{})  { l.record(fmt.Sprintln(args...)) }
+func (l *recordingLogger) Errorln(args ...interface{}) { l.record(fmt.Sprintln(args...)) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record(fmt.Sprintf(format, args...))
+}
+
+func TestEphemeralPort(t *testing.T) {
+	d := newTestDirector(t)
+	if strings.HasSuffix(d.NodeName(), ":0") {
+		t.Errorf("Expected the actual port to be advertised but got %v\n", d.NodeName())
+	}
+	remoteD := newTestDirector(t)
+	pid := remoteD.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer remoteD.Stop(pid)
+	if _, err := d.Call(pid, (*Phonebook).Add, "Jane", 1234); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
+
+func TestListenAddrOption(t *testing.T) {
+	// Advertised under another name than the listen address
+	d := mustNewDirector(t, "localhost:0", WithListenAddr("127.0.0.1:0"))
+	if !strings.HasPrefix(d.NodeName(), "localhost:") {
+		t.Errorf("Expected advertised host to be kept but got %v\n", d.NodeName())
+	}
+	pid := d.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer d.Stop(pid)
+	if _, err := newTestDirector(t).Call(pid, (*Phonebook).Add, "Jane", 1234); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
+
+func TestListenerOption(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &recordingLogger{}
+	clock := NewFakeClock(time.Now())
+	d := mustNewDirector(t, l.Addr().String(), WithListener(l), WithLogger(logger), WithClock(clock))
+	if d.Clock() != clock {
+		t.Error("Expected the clock option to be used")
+	}
+	logger.lock.Lock()
+	lines := logger.lines
+	logger.lock.Unlock()
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "Director listening at") {
+		t.Errorf("Expected the logger option to be used but got %v\n", lines)
+	}
+
+	pid := d.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer d.Stop(pid)
+	if _, err := newTestDirector(t).Call(pid, (*Phonebook).Add, "Jane", 1234); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
+
+func TestMaxMessageSizeOption(t *testing.T) {
+	remoteD := mustNewDirector(t, "127.0.0.1:0", WithMaxMessageSize(64*1024))
+	pid := remoteD.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer remoteD.Stop(pid)
+
+	d := newTestDirector(t)
+	if _, err := d.Call(pid, (*Phonebook).Add, "Jane", 1234); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+	if _, err := d.Call(pid, (*Phonebook).Add, strings.Repeat("x", 1<<20), 1234); err == nil {
+		t.Error("Expected a too large message to fail")
+	}
+}
+
+func TestDefaultMaxMessageSize(t *testing.T) {
+	remoteD := newTestDirector(t)
+	if size := remoteD.config.maxMessageSize; size != kDefaultMaxMessageSize {
+		t.Errorf("Expected %v but got %v\n", kDefaultMaxMessageSize, size)
+	}
+	pid := remoteD.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer remoteD.Stop(pid)
+
+	d := newTestDirector(t)
+	if _, err := d.Call(pid, (*Phonebook).Add, strings.Repeat("x", kDefaultMaxMessageSize+1), 1234); err == nil {
+		t.Error("Expected a too large message to fail")
+	}
+	if _, err := d.Call(pid, (*Phonebook).Add, "Jane", 1234); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
@@ -3,55 +3,53 @@ package cine
 import (
 	"net/rpc"
 	"reflect"
-	"runtime"
-	"strings"
 	"time"
 
 	"golang.org/x/net/context"
-
-	log "github.com/Sirupsen/logrus"
 )
 
 type RemoteActor struct {
 	pid      Pid
-	client   *rpc.Client
+	client   rpcClient
 	director *Director
 }
 
-func (r *RemoteActor) createRequest(function interface{}, args ...interface{}) RemoteRequest {
-	funcName := runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
-	tokens := strings.Split(funcName, ".")
-	funcName = tokens[len(tokens)-1]
-
+func (r *RemoteActor) createRequest(span SpanContext, function interface{}, args ...interface{}) RemoteRequest {
 	return RemoteRequest{
 		Pid:          r.pid,
-		FunctionName: funcName,
+		FunctionName: functionName(reflect.ValueOf(function)),
 		Args:         args,
+		Span:         span,
 	}
 }
 
-func (r *RemoteActor) call(function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
-	req := r.createRequest(function, args...)
+func (r *RemoteActor) call(span SpanContext, function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
+	req := r.createRequest(span, function, args...)
 
 	var resp RemoteResponse
 	call := r.client.Go("DirectorApi.HandleRemoteCall", req, &resp, nil)
 
 	err := r.handleCall(call)
+	if err == nil && resp.Err != nil {
+		err = canonicalError(resp.Err)
+	}
+	r.countCall(err)
 	if err != nil {
 		return nil, err
 	}
-	if resp.Err != nil {
-		return nil, resp.Err
-	}
 
 	return resp.Return, nil
 }
 
+func (r *RemoteActor) countCall(err *DirectorError) {
+	r.director.metrics.Add(kMetricRemoteCalls, Labels{"node": r.pid.NodeName, "outcome": callOutcome(err)}, 1)
+}
+
 func (r *RemoteActor) callWithContext(function interface{}, ctx context.Context, args ...interface{}) ([]interface{}, *DirectorError) {
-	req := r.createRequest(function, args...)
+	req := r.createRequest(SpanFromContext(ctx), function, args...)
 	var timeout time.Duration
 	if dl, ok := ctx.Deadline(); ok {
-		timeout = dl.Sub(time.Now())
+		timeout = dl.Sub(r.director.clock.Now())
 		if timeout <= 0 {
 			return nil, &DirectorError{context.DeadlineExceeded.Error()}
 		}
@@ -61,26 +59,58 @@ func (r *RemoteActor) callWithContext(function interface{}, ctx context.Context,
 	var resp RemoteResponse
 	call := r.client.Go("DirectorApi.HandleRemoteCallWithContext", req, &resp, nil)
 
-	err := r.handleCall(call)
+	// The director gives up at the deadline of ctx
+	callTimeout := r.director.config.callTimeout
+	if timeout > 0 {
+		callTimeout = 0
+	}
+	err := r.waitCall(call, callTimeout, ctx)
+	if err == nil && resp.Err != nil {
+		err = canonicalError(resp.Err)
+	}
+	r.countCall(err)
 	if err != nil {
 		return nil, err
 	}
-	if resp.Err != nil {
-		return nil, resp.Err
-	}
 
 	return resp.Return, nil
 }
 
 func (r *RemoteActor) handleCall(call *rpc.Call) *DirectorError {
-	<-call.Done
+	return r.waitCall(call, r.director.config.callTimeout, nil)
+}
+
+// waitCall waits for the reply of call until timeout or until ctx, which may
+// be nil, is done. Zero timeout waits forever.
+func (r *RemoteActor) waitCall(call *rpc.Call, timeout time.Duration, ctx context.Context) *DirectorError {
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var expired chan struct{}
+	if timeout > 0 {
+		expired = make(chan struct{})
+		t := r.director.clock.AfterFunc(timeout, func() { close(expired) })
+		defer t.Stop()
+	}
+	select {
+	case <-call.Done:
+	case <-expired:
+		r.director.logger.Warnln("Call", call.ServiceMethod, "to", r.pid.NodeName, "timed out")
+		return ErrCallTimeout
+	case <-done:
+		// The other director gives up at the same deadline, but its answer
+		// may be lost
+		return &DirectorError{ctx.Err().Error()}
+	}
+
 	if call.Error == rpc.ErrShutdown {
-		log.Errorln("Remote actor rpc.Client shutdown, returning ErrActorNotFound")
-		r.director.removeClient(r.pid)
+		r.director.logger.Errorln("Remote actor rpc.Client shutdown, returning ErrActorNotFound")
+		r.director.connectionLost(r.pid.NodeName, call.Error.Error())
 		// TODO(serialx): Add more specific error return
 		return ErrActorNotFound
 	} else if call.Error != nil {
-		log.Errorf("Remote actor call failed with: %v, returning ErrActorNotFound\n", call.Error)
+		r.director.logger.Errorf("Remote actor call failed with: %v, returning ErrActorNotFound\n", call.Error)
 		// TODO(serialx): Add more specific error return
 		return ErrActorNotFound
 	}
@@ -88,8 +118,8 @@ func (r *RemoteActor) handleCall(call *rpc.Call) *DirectorError {
 	return nil
 }
 
-func (r *RemoteActor) cast(done chan *ActorCall, function interface{}, args ...interface{}) {
-	req := r.createRequest(function, args...)
+func (r *RemoteActor) cast(span SpanContext, done chan *ActorCall, function interface{}, args ...interface{}) {
+	req := r.createRequest(span, function, args...)
 
 	var resp RemoteResponse
 	r.client.Go("DirectorApi.HandleRemoteCast", req, &resp, nil)
@@ -101,9 +131,12 @@ func (r *RemoteActor) stop() *DirectorError {
 	}
 
 	var resp RemoteResponse
-	r.client.Go("DirectorApi.HandleRemoteStop", req, &resp, nil)
+	call := r.client.Go("DirectorApi.HandleRemoteStop", req, &resp, nil)
+	if err := r.handleCall(call); err != nil {
+		return err
+	}
 	if resp.Err != nil {
-		return resp.Err
+		return canonicalError(resp.Err)
 	}
 	return nil
 }
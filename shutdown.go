@@ -0,0 +1,161 @@
+package cine
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const kDefaultShutdownTimeout = 5 * time.Second
+
+// trackingListener keeps track of accepted connections. Remote calls are served
+// on hijacked HTTP connections which http.Server does not close on shutdown.
+type trackingListener struct {
+	net.Listener
+	lock    sync.Mutex
+	conns   map[*trackedConn]bool
+	metrics Metrics
+}
+
+type trackedConn struct {
+	net.Conn
+	listener *trackingListener
+}
+
+func newTrackingListener(l net.Listener, metrics Metrics) *trackingListener {
+	return &trackingListener{Listener: l, conns: make(map[*trackedConn]bool), metrics: metrics}
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	c := &trackedConn{conn, l}
+	l.lock.Lock()
+	l.conns[c] = true
+	l.metrics.Set(kMetricConnections, nil, float64(len(l.conns)))
+	l.lock.Unlock()
+	l.metrics.Add(kMetricConnectionsTotal, nil, 1)
+	return c, nil
+}
+
+func (l *trackingListener) closeConns() {
+	l.lock.Lock()
+	conns := l.conns
+	l.conns = make(map[*trackedConn]bool)
+	l.metrics.Set(kMetricConnections, nil, 0)
+	l.lock.Unlock()
+
+	for c := range conns {
+		c.Conn.Close()
+	}
+}
+
+func (c *trackedConn) Close() error {
+	c.listener.lock.Lock()
+	delete(c.listener.conns, c)
+	c.listener.metrics.Set(kMetricConnections, nil, float64(len(c.listener.conns)))
+	c.listener.lock.Unlock()
+	return c.Conn.Close()
+}
+
+// SetShutdownTimeout sets how long Shutdown waits for each actor to terminate.
+func (d *Director) SetShutdownTimeout(timeout time.Duration) {
+	d.shutdownTimeout = timeout
+}
+
+// Shutdown stops accepting remote calls, stops the local actors in reverse
+// start order and closes the connections to other nodes. Terminate is called
+// with ErrShutdown. Each actor gets the shutdown timeout to terminate;
+// ErrShutdownTimeout is returned if some did not. ctx bounds the whole
+// shutdown. Afterwards spawns and activations fail with ErrShutdown and actors
+// started with StartActor are stopped right away.
+func (d *Director) Shutdown(ctx context.Context) error {
+	d.pidLock.Lock()
+	d.shutDown = true
+	d.pidLock.Unlock()
+
+	var result error
+	if err := d.server.Shutdown(ctx); err != nil {
+		result = err
+	}
+	// The server only closes the listener once Serve started, which may not be
+	// the case yet right after NewDirector
+	d.listener.Close()
+	d.listener.closeConns()
+	d.stopClusterTimers()
+
+	d.pidLock.RLock()
+	actors := make([]*Actor, 0, len(d.pidMap))
+	for _, actor := range d.pidMap {
+		actors = append(actors, actor)
+	}
+	d.pidLock.RUnlock()
+	sort.Slice(actors, func(i, j int) bool {
+		return actors[i].pid.ActorId > actors[j].pid.ActorId
+	})
+
+	for _, actor := range actors {
+		if err := d.shutdownActor(ctx, actor); err != nil {
+			if err != ErrShutdownTimeout {
+				// The whole shutdown ran out of time
+				result = err
+				break
+			}
+			if result == nil {
+				result = err
+			}
+		}
+	}
+
+	d.clientLock.Lock()
+	for node, client := range d.clientMap {
+		client.Close()
+		delete(d.clientMap, node)
+	}
+	d.clientLock.Unlock()
+
+	d.logger.Infoln("Director", d.nodeName, "shut down")
+	return result
+}
+
+func (d *Director) shutdownActor(ctx context.Context, actor *Actor) error {
+	actorCtx, cancel := ContextWithTimeout(d.clock, ctx, d.shutdownTimeout)
+	defer cancel()
+
+	actor.stopWithReason(ErrShutdown)
+	select {
+	case <-actor.terminated:
+		return nil
+	case <-actorCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		d.logger.Warnln("Actor", actor.pid, "did not terminate within", d.shutdownTimeout)
+		return ErrShutdownTimeout
+	}
+}
+
+// stopClusterTimers stops gossiping and heartbeats to monitored nodes.
+func (d *Director) stopClusterTimers() {
+	m := &d.membership
+	m.lock.Lock()
+	m.joined = false
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.lock.Unlock()
+
+	nm := &d.nodeMonitor
+	nm.lock.Lock()
+	for node, n := range nm.nodes {
+		n.timer.Stop()
+		delete(nm.nodes, node)
+		n.closeClient()
+	}
+	nm.lock.Unlock()
+}
@@ -0,0 +1,181 @@
+package cine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type ShutdownActor struct {
+	Actor
+	id      int
+	lock    *sync.Mutex
+	order   *[]int
+	reasons chan error
+	block   chan bool
+}
+
+func (a *ShutdownActor) Ping() bool {
+	return true
+}
+
+// Block blocks the actor thread until a second value is sent to block
+func (a *ShutdownActor) Block() {
+	<-a.block
+	<-a.block
+}
+
+func (a *ShutdownActor) ExposedMethods() []string {
+	return []string{"Ping", "Block"}
+}
+
+func (a *ShutdownActor) Terminate(errReason error) {
+	a.lock.Lock()
+	*a.order = append(*a.order, a.id)
+	a.lock.Unlock()
+	a.reasons <- errReason
+}
+
+func newShutdownActors(n int) []*ShutdownActor {
+	var lock sync.Mutex
+	var order []int
+	var actors []*ShutdownActor
+	for i := 0; i < n; i++ {
+		actors = append(actors, &ShutdownActor{Actor{}, i, &lock, &order, make(chan error, 1), make(chan bool)})
+	}
+	return actors
+}
+
+func TestNewDirectorPortTaken(t *testing.T) {
+	d := newTestDirector(t)
+	if _, err := NewDirector(d.nodeName); err == nil {
+		t.Error("Expected an error for a port in use")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	actors := newShutdownActors(3)
+	var pids []Pid
+	for _, a := range actors {
+		pids = append(pids, d.StartActor(a))
+	}
+	if _, err := remoteD.Call(pids[0], (*ShutdownActor).Ping); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	for _, a := range actors {
+		if reason := <-a.reasons; reason != ErrShutdown {
+			t.Errorf("Expected ErrShutdown but got %v\n", reason)
+		}
+	}
+	order := *actors[0].order
+	if len(order) != 3 || order[0] != 2 || order[1] != 1 || order[2] != 0 {
+		t.Errorf("Expected actors to terminate in reverse start order but got %v\n", order)
+	}
+
+	if _, err := remoteD.Call(pids[0], (*ShutdownActor).Ping); err == nil {
+		t.Error("Expected remote call to a shut down director to fail")
+	}
+	// The port is released
+	if _, err := NewDirector(d.nodeName); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	d := newTestDirector(t)
+	d.SetShutdownTimeout(10 * time.Millisecond)
+	actors := newShutdownActors(2)
+	stuck := actors[1]
+	d.StartActor(actors[0])
+	d.Cast(d.StartActor(stuck), nil, (*ShutdownActor).Block)
+	stuck.block <- true
+
+	if err := d.Shutdown(context.Background()); err != ErrShutdownTimeout {
+		t.Errorf("Expected ErrShutdownTimeout but got %v\n", err)
+	}
+	// Actors after the stuck one are still stopped
+	if reason := <-actors[0].reasons; reason != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", reason)
+	}
+
+	stuck.block <- true
+	if reason := <-stuck.reasons; reason != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", reason)
+	}
+}
+
+func TestShutdownClosesClients(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	pid := remoteD.StartActor(newShutdownActors(1)[0])
+	for i := 0; i < 5; i++ {
+		if _, err := d.Call(pid, (*ShutdownActor).Ping); err != nil {
+			t.Fatalf("Expected no error but got %v\n", err)
+		}
+	}
+	if n := openConns(remoteD); n != 1 {
+		t.Errorf("Expected calls to share one connection but got %d\n", n)
+	}
+	events, cancel := d.SubscribeNodes()
+	defer cancel()
+	d.MonitorNode(remoteD.nodeName)
+	expectNodeEvent(t, events, remoteD.nodeName, NodeUp)
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	waitFor(t, "client connections closed", func() bool {
+		return openConns(remoteD) == 0
+	})
+}
+
+func TestStartAfterShutdown(t *testing.T) {
+	d := newTestDirector(t)
+	d.RegisterFactory("shutdown", func(args ...interface{}) ActorImplementor {
+		return newShutdownActors(1)[0]
+	})
+	d.RegisterKind(Kind{Name: "counter", Factory: newCounter})
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+
+	a := newShutdownActors(1)[0]
+	pid := d.StartActor(a)
+	select {
+	case reason := <-a.reasons:
+		if reason != ErrShutdown {
+			t.Errorf("Expected ErrShutdown but got %v\n", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Actor started after shutdown was not stopped")
+	}
+	if _, err := d.Call(pid, (*ShutdownActor).Ping); err == nil {
+		t.Error("Expected call to an actor started after shutdown to fail")
+	}
+
+	if _, err := d.SpawnOn("", "shutdown"); err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", err)
+	}
+	if _, err := d.Activate(Identity{"counter", "jane"}); err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", err)
+	}
+	api := &DirectorApi{director: d}
+	var spawnResp SpawnResponse
+	api.HandleSpawn(SpawnRequest{Name: "shutdown"}, &spawnResp)
+	if spawnResp.Err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", spawnResp.Err)
+	}
+	var activateResp ActivateResponse
+	api.HandleActivate(ActivateRequest{Identity{"counter", "jane"}}, &activateResp)
+	if activateResp.Err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown but got %v\n", activateResp.Err)
+	}
+}
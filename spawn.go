@@ -0,0 +1,154 @@
+package cine
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Factory creates an actor from the arguments given to SpawnOn.
+type Factory func(args ...interface{}) ActorImplementor
+
+type factories struct {
+	lock      sync.RWMutex
+	factories map[string]Factory
+}
+
+// SpawnOptions sets up supervision of a spawned actor before SpawnOn returns.
+// Zero pids are ignored.
+type SpawnOptions struct {
+	// Link links the spawned actor with the pid
+	Link Pid
+	// Monitor makes the pid monitor the spawned actor
+	Monitor Pid
+}
+
+type SpawnRequest struct {
+	Name    string
+	Args    []interface{}
+	Options SpawnOptions
+}
+
+type SpawnResponse struct {
+	Err *DirectorError
+	Pid Pid
+}
+
+// RegisterFactory registers a factory that other nodes can start actors with
+// using SpawnOn.
+func (d *Director) RegisterFactory(name string, factory Factory) {
+	d.factories.lock.Lock()
+	defer d.factories.lock.Unlock()
+	d.factories.factories[name] = factory
+}
+
+// SpawnOn starts an actor on node with the factory registered under name.
+// Arguments must be encodable with gob. ErrInvalidArgs is returned if the
+// factory panics or returns nil.
+func (d *Director) SpawnOn(node string, name string, args ...interface{}) (Pid, error) {
+	return d.SpawnOnWithOptions(node, name, SpawnOptions{}, args...)
+}
+
+// SpawnOnWithOptions is like SpawnOn but also links or monitors the spawned
+// actor.
+func (d *Director) SpawnOnWithOptions(node string, name string, opts SpawnOptions, args ...interface{}) (Pid, error) {
+	if node == "" || node == d.nodeName {
+		pid, err := d.spawnLocal(name, opts, args)
+		if err != nil {
+			return Pid{}, err
+		}
+		return pid, nil
+	}
+
+	var resp SpawnResponse
+	req := SpawnRequest{Name: name, Args: args, Options: opts}
+	if err := d.remoteCall(node, "HandleSpawn", req, &resp); err != nil {
+		return Pid{}, err
+	}
+	if resp.Err != nil {
+		return Pid{}, canonicalError(resp.Err)
+	}
+	return resp.Pid, nil
+}
+
+func (d *Director) spawnLocal(name string, opts SpawnOptions, args []interface{}) (Pid, *DirectorError) {
+	if d.isShutDown() {
+		return Pid{}, ErrShutdown
+	}
+	d.factories.lock.RLock()
+	factory, ok := d.factories.factories[name]
+	d.factories.lock.RUnlock()
+	if !ok {
+		return Pid{}, ErrFactoryNotFound
+	}
+
+	impl, ok := d.build(name, factory, args)
+	if !ok {
+		return Pid{}, ErrInvalidArgs
+	}
+	pid := d.StartActor(impl)
+	if opts.Monitor != (Pid{}) {
+		if err := d.Monitor(opts.Monitor, pid); err != nil {
+			d.Stop(pid)
+			return Pid{}, err
+		}
+	}
+	if opts.Link != (Pid{}) {
+		if err := d.Link(opts.Link, pid); err != nil {
+			d.Stop(pid)
+			return Pid{}, err
+		}
+	}
+	return pid, nil
+}
+
+// build calls factory. Arguments come from other nodes, so a factory panicking
+// on them, e.g. in a type assertion, must not bring the node down.
+func (d *Director) build(name string, factory Factory, args []interface{}) (impl ActorImplementor, ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			d.logger.Warnln("Factory", name, "panicked:", e)
+			ok = false
+		}
+	}()
+	impl = factory(args...)
+	if impl == nil {
+		return nil, false
+	}
+	// A nil pointer in the interface is nil as well
+	if v := reflect.ValueOf(impl); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	return impl, true
+}
+
+// kSpawnMethod is the method name remote spawn requests are authorized with.
+const kSpawnMethod = "Spawn"
+
+func (d *DirectorApi) HandleSpawn(r SpawnRequest, reply *SpawnResponse) error {
+	if err := d.authorize(Pid{NodeName: d.director.nodeName}, kSpawnMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	// The spawned actor stops a local Link when it dies
+	if link := r.Options.Link; link != (Pid{}) && link.NodeName == d.director.nodeName {
+		err := d.authorize(link, kLinkMethod)
+		if err == nil {
+			err = d.authorize(link, kStopMethod)
+		}
+		if err != nil {
+			reply.Err = err
+			return nil
+		}
+	}
+	if err := d.checkArgs(r.Args); err != nil {
+		reply.Err = err
+		return nil
+	}
+	pid, err := d.director.spawnLocal(r.Name, r.Options, r.Args)
+	if err != nil {
+		reply.Err = err
+		return nil
+	}
+	reply.Pid = pid
+	return nil
+}
@@ -0,0 +1,84 @@
+package cine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSpawnOn(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	reasons := make(chan error, 10)
+	remoteD.RegisterFactory("worker", func(args ...interface{}) ActorImplementor {
+		return &Worker{Actor{}, args[0].(string), reasons}
+	})
+
+	pid, err := d.SpawnOn(remoteD.nodeName, "worker", "w1")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if pid.NodeName != remoteD.nodeName {
+		t.Errorf("Expected actor on %v but got %v\n", remoteD.nodeName, pid)
+	}
+	r, callErr := d.Call(pid, (*Worker).Name)
+	if callErr != nil {
+		t.Fatalf("Expected no error but got %v\n", callErr)
+	}
+	if r[0].(string) != "w1" {
+		t.Errorf("Expected w1 but got %v\n", r[0])
+	}
+	d.Stop(pid)
+	expectReason(t, reasons, ErrActorStop)
+
+	if _, err := d.SpawnOn(remoteD.nodeName, "unknown"); err != ErrFactoryNotFound {
+		t.Errorf("Expected ErrFactoryNotFound but got %v\n", err)
+	}
+	if _, err := d.SpawnOn(d.nodeName, "worker", "w2"); err != ErrFactoryNotFound {
+		t.Errorf("Expected ErrFactoryNotFound locally but got %v\n", err)
+	}
+}
+
+func TestSpawnOnWithOptions(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := newTestDirector(t)
+	remoteD.RegisterFactory("worker", func(args ...interface{}) ActorImplementor {
+		return &Worker{Actor{}, args[0].(string), nil}
+	})
+
+	watcher := &Watcher{Actor{}, make(chan string, 10)}
+	watcherPid := d.StartActor(watcher)
+	defer d.Stop(watcherPid)
+	pid, err := d.SpawnOnWithOptions(remoteD.nodeName, "worker", SpawnOptions{Monitor: watcherPid}, "monitored")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	d.Cast(pid, nil, (*Worker).Crash)
+	expectString(t, watcher.downs, fmt.Sprintf("%v Actor panic: crash", pid))
+
+	owner := &Worker{Actor{}, "owner", make(chan error, 1)}
+	ownerPid := d.StartActor(owner)
+	pid, err = d.SpawnOnWithOptions(remoteD.nodeName, "worker", SpawnOptions{Link: ownerPid}, "linked")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	d.Cast(pid, nil, (*Worker).Crash)
+	expectReason(t, owner.reasons, ErrLinkedActorDied)
+}
+
+func TestSpawnOnInvalidArgs(t *testing.T) {
+	d := newTestDirector(t)
+	remoteD := mustNewDirector(t, "127.0.0.1:0", WithMaxArgs(2))
+	remoteD.RegisterFactory("worker", func(args ...interface{}) ActorImplementor {
+		return &Worker{Actor{}, args[0].(string), nil}
+	})
+
+	if _, err := d.SpawnOn(remoteD.nodeName, "worker", 42); err != ErrInvalidArgs {
+		t.Errorf("Expected ErrInvalidArgs but got %v\n", err)
+	}
+	if _, err := d.SpawnOn(remoteD.nodeName, "worker", "a", "b", "c"); err != ErrTooManyArgs {
+		t.Errorf("Expected ErrTooManyArgs but got %v\n", err)
+	}
+	if _, err := d.SpawnOn(remoteD.nodeName, "worker", "survived"); err != nil {
+		t.Errorf("Expected the node to survive the panicking factory but got %v\n", err)
+	}
+}
@@ -0,0 +1,190 @@
+package cine
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// StateSnapshotter is implemented by actors that control what GetState
+// returns. SnapshotState is called in the actor thread and must not return
+// anything the actor keeps modifying, like its own maps.
+type StateSnapshotter interface {
+	SnapshotState() interface{}
+}
+
+// StateReplacer is implemented by actors whose state can be replaced by a new
+// value with ReplaceState, like a snapshot returned by SnapshotState.
+// ReplaceState is called in the actor thread.
+type StateReplacer interface {
+	ReplaceState(state interface{}) error
+}
+
+type StateRequest struct {
+	Pid Pid
+}
+
+type StateResponse struct {
+	Err   *DirectorError
+	State interface{}
+}
+
+// kGetStateMethod is the method name remote state requests are authorized
+// with.
+const kGetStateMethod = "GetState"
+
+// systemCall is a function run by the message loop ahead of the messages in
+// the queue.
+type systemCall struct {
+	fn   func()
+	done chan struct{}
+}
+
+// system runs fn in the actor thread, waking the actor up if it is
+// hibernated, and waits until it returned. A panic in fn crashes the actor
+// like a panic in a handler. Like a call, it must not be made by the actor on
+// itself.
+func (r *Actor) system(fn func()) *DirectorError {
+	r.aliveLock.Lock()
+	if !r.alive {
+		r.aliveLock.Unlock()
+		return ErrActorStop
+	}
+	if r.hibernated {
+		r.wake()
+	}
+	// Keeps the actor from hibernating before it got the call
+	r.inflight += 1
+	r.aliveLock.Unlock()
+
+	call := &systemCall{fn, make(chan struct{})}
+	select {
+	case r.systemCh <- call:
+		r.releaseQueue()
+	case <-r.terminated:
+		r.releaseQueue()
+		return ErrActorDied
+	}
+	select {
+	case <-call.done:
+		return nil
+	case <-r.terminated:
+		return ErrActorDied
+	}
+}
+
+// snapshotState returns the state of the actor. Inspecting an actor must not
+// crash it, so a panic of SnapshotState is returned as an error. Must be called
+// within the actor thread.
+func (r *Actor) snapshotState() (state interface{}, err *DirectorError) {
+	defer func() {
+		if e := recover(); e != nil {
+			state, err = nil, &DirectorError{fmt.Sprint("Snapshotting state panicked: ", e)}
+		}
+	}()
+	if snapshotter, ok := r.receiver.Interface().(StateSnapshotter); ok {
+		return snapshotter.SnapshotState(), nil
+	}
+	// Formatting the fields here copies them before the actor changes them
+	fields := make(map[string]string)
+	value := reflect.Indirect(r.receiver)
+	if value.Kind() != reflect.Struct {
+		fields[""] = fmt.Sprintf("%+v", value)
+		return fields, nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous && field.Type == reflect.TypeOf(Actor{}) {
+			continue
+		}
+		fields[field.Name] = fmt.Sprintf("%+v", value.Field(i))
+	}
+	return fields, nil
+}
+
+// GetState returns a snapshot of the state of an actor, taken in its thread
+// ahead of the queued messages. Actors implementing StateSnapshotter return
+// their own snapshot, others return the fields of the actor, except the
+// embedded Actor, formatted as strings in a map[string]string. A panic of
+// SnapshotState is returned as an error and the actor keeps running.
+//
+// Snapshots of actors on other nodes must be registered with gob.
+func (d *Director) GetState(pid Pid) (interface{}, *DirectorError) {
+	if pid.NodeName != d.nodeName {
+		var resp StateResponse
+		if err := d.remoteCall(pid.NodeName, "HandleGetState", StateRequest{pid}, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Err != nil {
+			return nil, canonicalError(resp.Err)
+		}
+		return resp.State, nil
+	}
+	actor, err := d.localActorFromPid(pid)
+	if err != nil {
+		return nil, connectError(err, ErrActorNotFound)
+	}
+	var state interface{}
+	var snapshotErr *DirectorError
+	if err := actor.system(func() { state, snapshotErr = actor.snapshotState() }); err != nil {
+		return nil, err
+	}
+	return state, snapshotErr
+}
+
+// ReplaceState runs replace in the thread of a local actor, ahead of the
+// queued messages, to fix its state. replace gets the actor, like
+// *main.Phonebook, and returns either the same actor after changing it or a
+// new state passed to the ReplaceState method of actors implementing
+// StateReplacer. Other values fail with ErrInvalidArgs. Errors and panics of
+// replace and of the StateReplacer are returned and the actor keeps running.
+// Functions cannot be sent to other nodes, so pids of other nodes fail with
+// ErrActorNotFound.
+func (d *Director) ReplaceState(pid Pid, replace func(state interface{}) interface{}) *DirectorError {
+	actor, err := d.localActorFromPid(pid)
+	if err != nil {
+		return connectError(err, ErrActorNotFound)
+	}
+	var replaceErr *DirectorError
+	if err := actor.system(func() {
+		replaceErr = actor.replaceState(replace)
+	}); err != nil {
+		return err
+	}
+	return replaceErr
+}
+
+// replaceState replaces the state of the actor with the value returned by
+// replace. Must be called within the actor thread.
+func (r *Actor) replaceState(replace func(state interface{}) interface{}) (err *DirectorError) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = &DirectorError{fmt.Sprint("Replacing state panicked: ", e)}
+		}
+	}()
+	receiver := r.receiver.Interface()
+	state := replace(receiver)
+	if state == receiver {
+		return nil
+	}
+	replacer, ok := receiver.(StateReplacer)
+	if !ok {
+		return ErrInvalidArgs
+	}
+	if err := replacer.ReplaceState(state); err != nil {
+		return &DirectorError{err.Error()}
+	}
+	return nil
+}
+
+func (d *DirectorApi) HandleGetState(req StateRequest, reply *StateResponse) error {
+	if err := d.authorize(req.Pid, kGetStateMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	if req.Pid.NodeName != d.director.nodeName {
+		reply.Err = ErrActorNotFound
+		return nil
+	}
+	reply.State, reply.Err = d.director.GetState(req.Pid)
+	return nil
+}
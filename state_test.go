@@ -0,0 +1,200 @@
+package cine
+
+import (
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+)
+
+type Session struct {
+	Actor
+	user  string
+	items []string
+}
+
+func (s *Session) Items() []string {
+	return s.items
+}
+
+func (s *Session) SnapshotState() interface{} {
+	return SessionState{s.user, append([]string(nil), s.items...)}
+}
+
+func (s *Session) ReplaceState(state interface{}) error {
+	snapshot, ok := state.(SessionState)
+	if !ok {
+		return errors.New("not a session state")
+	}
+	s.user = snapshot.User
+	s.items = snapshot.Items
+	return nil
+}
+
+func (s *Session) Terminate(errReason error) {
+}
+
+type SessionState struct {
+	User  string
+	Items []string
+}
+
+func init() {
+	gob.Register(SessionState{})
+}
+
+func TestGetState(t *testing.T) {
+	d := newTestDirector(t)
+	book := &Phonebook{Actor{}, map[string]int{"Jane": 1234}}
+	pid := d.StartActor(book)
+
+	state, err := d.GetState(pid)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	fields, ok := state.(map[string]string)
+	if !ok || fields["book"] != "map[Jane:1234]" || len(fields) != 1 {
+		t.Errorf("Expected the fields of the phonebook but got %v\n", state)
+	}
+
+	// Hibernated actors are woken up
+	clock := NewFakeClock(time.Now())
+	d.SetClock(clock)
+	session := &Session{user: "jane", items: []string{"sword"}}
+	session.SetIdleTimeout(time.Minute)
+	session.SetHibernate(true)
+	pid = d.StartActor(session)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	waitFor(t, "hibernation", session.Hibernated)
+	state, err = d.GetState(pid)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if s, ok := state.(SessionState); !ok || s.User != "jane" || len(s.Items) != 1 {
+		t.Errorf("Expected the snapshot of the session but got %v\n", state)
+	}
+
+	d.Stop(pid)
+	waitFor(t, "stop", func() bool {
+		_, err := d.localActorFromPid(pid)
+		return err != nil
+	})
+	if _, err := d.GetState(pid); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+}
+
+func TestGetStateRemote(t *testing.T) {
+	d1 := newTestDirector(t)
+	d2 := newTestDirector(t)
+	pid := d1.StartActor(&Session{user: "jane", items: []string{"sword", "shield"}})
+
+	state, err := d2.GetState(pid)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if s, ok := state.(SessionState); !ok || s.User != "jane" || len(s.Items) != 2 {
+		t.Errorf("Expected the snapshot of the session but got %v\n", state)
+	}
+
+	d3 := mustNewDirector(t, "127.0.0.1:0", WithAuthorizer(func(node string, pid Pid, method string) bool {
+		return method != kGetStateMethod
+	}))
+	pid = d3.StartActor(&Session{user: "john"})
+	if _, err := d2.GetState(pid); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+}
+
+// BrokenSnapshot panics when its state is inspected
+type BrokenSnapshot struct {
+	Actor
+}
+
+func (b *BrokenSnapshot) Ping() bool {
+	return true
+}
+
+func (b *BrokenSnapshot) SnapshotState() interface{} {
+	panic("no snapshot")
+}
+
+func (b *BrokenSnapshot) Terminate(errReason error) {
+}
+
+func TestGetStatePanic(t *testing.T) {
+	d := newTestDirector(t)
+	pid := d.StartActor(&BrokenSnapshot{})
+	defer d.Stop(pid)
+
+	if _, err := d.GetState(pid); err == nil {
+		t.Error("Expected a panicking snapshot to fail")
+	}
+	// Over the network as well
+	if _, err := newTestDirector(t).GetState(pid); err == nil {
+		t.Error("Expected a panicking snapshot to fail")
+	}
+	if _, err := d.Call(pid, (*BrokenSnapshot).Ping); err != nil {
+		t.Errorf("Expected the actor to survive but got %v\n", err)
+	}
+}
+
+func TestReplaceState(t *testing.T) {
+	d := newTestDirector(t)
+	pid := d.StartActor(&Session{user: "jane", items: []string{"sword"}})
+
+	// Changed in place
+	err := d.ReplaceState(pid, func(state interface{}) interface{} {
+		s := state.(*Session)
+		s.items = append(s.items, "shield")
+		return s
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r, _ := d.Call(pid, (*Session).Items); len(r[0].([]string)) != 2 {
+		t.Errorf("Expected 2 items but got %v\n", r[0])
+	}
+
+	// Replaced by a new state, the actor keeps running
+	err = d.ReplaceState(pid, func(state interface{}) interface{} {
+		return SessionState{User: "jane", Items: []string{"bow"}}
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	r, err := d.Call(pid, (*Session).Items)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if items := r[0].([]string); len(items) != 1 || items[0] != "bow" {
+		t.Errorf("Expected the replaced items but got %v\n", items)
+	}
+
+	err = d.ReplaceState(pid, func(state interface{}) interface{} {
+		return &Session{}
+	})
+	if err == nil || err.Error() != "not a session state" {
+		t.Errorf("Expected the error of the actor but got %v\n", err)
+	}
+	err = d.ReplaceState(pid, func(state interface{}) interface{} {
+		panic("oops")
+	})
+	if err == nil {
+		t.Error("Expected a panic to fail")
+	}
+	if r, err := d.Call(pid, (*Session).Items); err != nil || len(r[0].([]string)) != 1 {
+		t.Errorf("Expected the actor to survive the panic but got %v %v\n", r, err)
+	}
+
+	// Actors that are not StateReplacers can only be changed in place
+	book := d.StartActor(&Phonebook{Actor{}, make(map[string]int)})
+	defer d.Stop(book)
+	err = d.ReplaceState(book, func(state interface{}) interface{} {
+		return &Phonebook{Actor{}, map[string]int{"Jane": 1234}}
+	})
+	if err != ErrInvalidArgs {
+		t.Errorf("Expected ErrInvalidArgs but got %v\n", err)
+	}
+}
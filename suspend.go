@@ -0,0 +1,86 @@
+package cine
+
+type SuspendRequest struct {
+	Pid     Pid
+	Suspend bool
+}
+
+type SuspendResponse struct {
+	Err *DirectorError
+}
+
+// Method names remote suspend and resume requests are authorized with.
+const (
+	kSuspendMethod = "Suspend"
+	kResumeMethod  = "Resume"
+)
+
+// Suspend pauses the handling of messages by an actor, which may be on
+// another node. Messages are still accepted and queued, and are handled in
+// order once the actor is resumed. Only WithMaxRemoteQueued bounds the queue,
+// for messages of other nodes; local casts keep piling up. The actor can still
+// be stopped and its state inspected while suspended. Suspending a suspended
+// actor does nothing. Like Call, it must not be used by an actor on itself;
+// handlers use Actor.Suspend instead.
+func (d *Director) Suspend(pid Pid) *DirectorError {
+	return d.setSuspended(pid, true)
+}
+
+// Resume resumes an actor paused by Suspend.
+func (d *Director) Resume(pid Pid) *DirectorError {
+	return d.setSuspended(pid, false)
+}
+
+func (d *Director) setSuspended(pid Pid, suspended bool) *DirectorError {
+	if pid.NodeName != d.nodeName {
+		var resp SuspendResponse
+		if err := d.remoteCall(pid.NodeName, "HandleSuspend", SuspendRequest{pid, suspended}, &resp); err != nil {
+			return err
+		}
+		if resp.Err != nil {
+			return canonicalError(resp.Err)
+		}
+		return nil
+	}
+	actor, err := d.localActorFromPid(pid)
+	if err != nil {
+		return connectError(err, ErrActorNotFound)
+	}
+	return actor.system(func() {
+		actor.aliveLock.Lock()
+		actor.suspended = suspended
+		actor.aliveLock.Unlock()
+	})
+}
+
+// Suspend pauses the actor once the running handler returned, until it is
+// resumed with Director.Resume. It must be called from the actor goroutine.
+func (r *Actor) Suspend() {
+	r.aliveLock.Lock()
+	r.suspended = true
+	r.aliveLock.Unlock()
+}
+
+// Suspended returns true if the actor is suspended.
+func (r *Actor) Suspended() bool {
+	r.aliveLock.Lock()
+	defer r.aliveLock.Unlock()
+	return r.suspended
+}
+
+func (d *DirectorApi) HandleSuspend(req SuspendRequest, reply *SuspendResponse) error {
+	method := kResumeMethod
+	if req.Suspend {
+		method = kSuspendMethod
+	}
+	if err := d.authorize(req.Pid, method); err != nil {
+		reply.Err = err
+		return nil
+	}
+	if req.Pid.NodeName != d.director.nodeName {
+		reply.Err = ErrActorNotFound
+		return nil
+	}
+	reply.Err = d.director.setSuspended(req.Pid, req.Suspend)
+	return nil
+}
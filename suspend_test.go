@@ -0,0 +1,161 @@
+package cine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuspend(t *testing.T) {
+	d := newTestDirector(t)
+	counter := &Counter{key: "suspended"}
+	pid := d.StartActor(counter)
+
+	if err := d.Suspend(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if !counter.Suspended() {
+		t.Errorf("Expected the actor to be suspended\n")
+	}
+	for i := 0; i < 3; i++ {
+		d.Cast(pid, nil, (*Counter).Incr)
+	}
+	result := make(chan int, 1)
+	go func() {
+		r, err := d.Call(pid, (*Counter).Incr)
+		if err != nil {
+			t.Errorf("Expected no error but got %v\n", err)
+		}
+		result <- r[0].(int)
+	}()
+	waitFor(t, "queued messages", func() bool {
+		infos := d.Actors()
+		return len(infos) == 1 && infos[0].Mailbox == 4 && infos[0].Suspended
+	})
+	select {
+	case n := <-result:
+		t.Fatalf("Expected the call to wait for Resume but got %v\n", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// System messages are still handled
+	state, err := d.GetState(pid)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if fields := state.(map[string]string); fields["count"] != "0" {
+		t.Errorf("Expected no message to be handled but got %v\n", fields)
+	}
+
+	if err := d.Resume(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if n := <-result; n != 4 {
+		t.Errorf("Expected the call to be handled after the casts but got %v\n", n)
+	}
+	if counter.Suspended() {
+		t.Errorf("Expected the actor to be resumed\n")
+	}
+}
+
+func TestSuspendStop(t *testing.T) {
+	d := newTestDirector(t)
+	pid := d.StartActor(&Counter{})
+	d.Suspend(pid)
+
+	result := make(chan *DirectorError, 1)
+	go func() {
+		_, err := d.Call(pid, (*Counter).Incr)
+		result <- err
+	}()
+	waitFor(t, "queued call", func() bool {
+		infos := d.Actors()
+		return len(infos) == 1 && infos[0].Mailbox == 1
+	})
+	if err := d.Stop(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if err := <-result; err != ErrActorDied {
+		t.Errorf("Expected ErrActorDied but got %v\n", err)
+	}
+	if err := d.Resume(pid); err != ErrActorNotFound {
+		t.Errorf("Expected ErrActorNotFound but got %v\n", err)
+	}
+}
+
+func TestSuspendRemote(t *testing.T) {
+	d1 := newTestDirector(t)
+	d2 := newTestDirector(t)
+	counter := &Counter{}
+	pid := d1.StartActor(counter)
+
+	if err := d2.Suspend(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if !counter.Suspended() {
+		t.Errorf("Expected the actor to be suspended\n")
+	}
+	d2.Cast(pid, nil, (*Counter).Incr)
+	waitFor(t, "queued cast", func() bool {
+		return d1.Actors()[0].Mailbox == 1
+	})
+	if err := d2.Resume(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	r, err := d2.Call(pid, (*Counter).Incr)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(int) != 2 {
+		t.Errorf("Expected the queued cast to be handled but got %v\n", r[0])
+	}
+
+	d3 := mustNewDirector(t, "127.0.0.1:0", WithAuthorizer(func(node string, pid Pid, method string) bool {
+		return method != kSuspendMethod
+	}))
+	pid = d3.StartActor(&Counter{})
+	if err := d2.Suspend(pid); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied but got %v\n", err)
+	}
+}
+
+// SelfSuspender suspends itself from a handler
+type SelfSuspender struct {
+	Actor
+	count int
+}
+
+func (s *SelfSuspender) Incr() int {
+	s.count += 1
+	if s.count == 1 {
+		s.Suspend()
+	}
+	return s.count
+}
+
+func (s *SelfSuspender) Terminate(errReason error) {
+}
+
+func TestSuspendSelf(t *testing.T) {
+	d := newTestDirector(t)
+	actor := &SelfSuspender{}
+	pid := d.StartActor(actor)
+	defer d.Stop(pid)
+
+	if r, err := d.Call(pid, (*SelfSuspender).Incr); err != nil || r[0].(int) != 1 {
+		t.Fatalf("Expected 1 but got %v %v\n", r, err)
+	}
+	if !actor.Suspended() {
+		t.Fatal("Expected the actor to be suspended")
+	}
+	d.Cast(pid, nil, (*SelfSuspender).Incr)
+	waitFor(t, "queued message", func() bool {
+		infos := d.Actors()
+		return len(infos) == 1 && infos[0].Mailbox == 1
+	})
+	if err := d.Resume(pid); err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r, err := d.Call(pid, (*SelfSuspender).Incr); err != nil || r[0].(int) != 3 {
+		t.Errorf("Expected 3 but got %v %v\n", r, err)
+	}
+}
@@ -0,0 +1,123 @@
+package cine
+
+import (
+	"sync"
+	"time"
+)
+
+// TimerRef identifies a timer started with SendAfter, SendAfterTo or Every.
+// It can be passed to CancelTimer of the actor that started the timer.
+type TimerRef struct {
+	id int64
+}
+
+type actorTimer struct {
+	timer  Timer
+	period time.Duration
+}
+
+// actorTimers holds the timers owned by an actor. Timers fire in their own
+// goroutines so the map must be protected with a lock.
+type actorTimers struct {
+	lock   sync.Mutex
+	maxId  int64
+	timers map[int64]*actorTimer
+}
+
+// SendAfter casts function to the actor itself after duration d. The message
+// is delivered through the normal mailbox so it never blocks the actor.
+func (r *Actor) SendAfter(d time.Duration, function interface{}, args ...interface{}) TimerRef {
+	r.verifyCallSignature(function, args)
+	span := r.Span()
+	return r.startTimer(d, 0, func() {
+		r.cast(span, nil, function, args...)
+	})
+}
+
+// SendAfterTo casts function to pid after duration d. pid may be a local or a
+// remote actor. The timer is owned by this actor and is cancelled when this
+// actor terminates. The signature is verified right away for local actors
+// only.
+func (r *Actor) SendAfterTo(pid Pid, d time.Duration, function interface{}, args ...interface{}) TimerRef {
+	if r.director == nil {
+		panic("SendAfterTo requires an actor started by a Director")
+	}
+	if pid.NodeName == r.director.nodeName {
+		if target, err := r.director.localActorFromPid(pid); err == nil {
+			target.verifyCallSignature(function, args)
+		}
+	}
+	span := r.Span()
+	return r.startTimer(d, 0, func() {
+		r.director.cast(span, pid, nil, function, args...)
+	})
+}
+
+// Every casts function to the actor itself every period until the timer is
+// cancelled or the actor terminates.
+func (r *Actor) Every(period time.Duration, function interface{}, args ...interface{}) TimerRef {
+	if period <= 0 {
+		panic("Every requires a positive period")
+	}
+	r.verifyCallSignature(function, args)
+	span := r.Span()
+	return r.startTimer(period, period, func() {
+		r.cast(span, nil, function, args...)
+	})
+}
+
+// CancelTimer cancels the timer. It returns false if the timer already fired
+// (for one-shot timers) or was already cancelled.
+func (r *Actor) CancelTimer(ref TimerRef) bool {
+	r.timers.lock.Lock()
+	defer r.timers.lock.Unlock()
+
+	t, ok := r.timers.timers[ref.id]
+	if !ok {
+		return false
+	}
+	delete(r.timers.timers, ref.id)
+	t.timer.Stop()
+	return true
+}
+
+func (r *Actor) startTimer(d time.Duration, period time.Duration, fire func()) TimerRef {
+	r.timers.lock.Lock()
+	defer r.timers.lock.Unlock()
+
+	if r.timers.timers == nil {
+		r.timers.timers = make(map[int64]*actorTimer)
+	}
+	r.timers.maxId += 1
+	id := r.timers.maxId
+	t := &actorTimer{period: period}
+	t.timer = r.clock().AfterFunc(d, func() {
+		r.timers.lock.Lock()
+		if _, ok := r.timers.timers[id]; !ok {
+			// Cancelled while firing
+			r.timers.lock.Unlock()
+			return
+		}
+		if t.period == 0 {
+			delete(r.timers.timers, id)
+		} else {
+			t.timer.Reset(t.period)
+		}
+		r.timers.lock.Unlock()
+
+		fire()
+	})
+	r.timers.timers[id] = t
+	return TimerRef{id}
+}
+
+// cancelAllTimers stops every timer owned by the actor.
+func (r *Actor) cancelAllTimers() {
+	r.timers.lock.Lock()
+	defer r.timers.lock.Unlock()
+
+	for id, t := range r.timers.timers {
+		t.timer.Stop()
+		delete(r.timers.timers, id)
+	}
+}
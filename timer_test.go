@@ -0,0 +1,140 @@
+package cine
+
+import (
+	"testing"
+	"time"
+)
+
+type TimerActor struct {
+	Actor
+	ticks chan int
+	count int
+}
+
+func (a *TimerActor) Tick(x int) {
+	a.count += 1
+	a.ticks <- x
+}
+
+func (a *TimerActor) Count() int {
+	return a.count
+}
+
+func (a *TimerActor) Terminate(errReason error) {
+}
+
+func newTimerActor() *TimerActor {
+	a := &TimerActor{Actor{}, make(chan int, 10), 0}
+	a.startMessageLoop(a)
+	return a
+}
+
+func TestSendAfter(t *testing.T) {
+	a := newTimerActor()
+	defer a.stop()
+
+	a.SendAfter(10*time.Millisecond, (*TimerActor).Tick, 7)
+	select {
+	case x := <-a.ticks:
+		if x != 7 {
+			t.Errorf("Expected 7 but got %v\n", x)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timer did not fire")
+	}
+
+	ref := a.SendAfter(10*time.Millisecond, (*TimerActor).Tick, 8)
+	if !a.CancelTimer(ref) {
+		t.Error("Expected CancelTimer to return true")
+	}
+	if a.CancelTimer(ref) {
+		t.Error("Expected second CancelTimer to return false")
+	}
+	select {
+	case x := <-a.ticks:
+		t.Errorf("Cancelled timer fired with %v\n", x)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEvery(t *testing.T) {
+	a := newTimerActor()
+	defer a.stop()
+
+	ref := a.Every(5*time.Millisecond, (*TimerActor).Tick, 1)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-a.ticks:
+		case <-time.After(time.Second):
+			t.Fatal("Periodic timer did not fire")
+		}
+	}
+	a.CancelTimer(ref)
+
+	r, err := a.call(SpanContext{}, (*TimerActor).Count)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if count := r[0].(int); count < 3 {
+		t.Errorf("Expected at least 3 ticks but got %v\n", count)
+	}
+}
+
+func TestTimersCancelledOnStop(t *testing.T) {
+	a := newTimerActor()
+	a.Every(5*time.Millisecond, (*TimerActor).Tick, 1)
+	a.SendAfter(time.Hour, (*TimerActor).Tick, 2)
+	a.stop()
+
+	// terminateActor runs asynchronously in the actor thread
+	deadline := time.Now().Add(time.Second)
+	for {
+		a.timers.lock.Lock()
+		n := len(a.timers.timers)
+		a.timers.lock.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no timers after stop but got %d\n", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSendAfterTo(t *testing.T) {
+	d := newTestDirector(t)
+	a := &TimerActor{Actor{}, make(chan int, 10), 0}
+	b := &TimerActor{Actor{}, make(chan int, 10), 0}
+	d.StartActor(a)
+	pid := d.StartActor(b)
+	defer d.Stop(a.Self())
+	defer d.Stop(pid)
+
+	a.SendAfterTo(pid, 10*time.Millisecond, (*TimerActor).Tick, 3)
+	select {
+	case x := <-b.ticks:
+		if x != 3 {
+			t.Errorf("Expected 3 but got %v\n", x)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timer did not fire")
+	}
+}
+
+func TestSendAfterToVerifiesSignature(t *testing.T) {
+	d := newTestDirector(t)
+	a := &TimerActor{Actor{}, make(chan int, 10), 0}
+	b := &TimerActor{Actor{}, make(chan int, 10), 0}
+	d.StartActor(a)
+	pid := d.StartActor(b)
+	defer d.Stop(a.Self())
+	defer d.Stop(pid)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a wrong signature to panic at the call site")
+		}
+	}()
+	a.SendAfterTo(pid, time.Minute, (*TimerActor).Tick, "not an int")
+}
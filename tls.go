@@ -0,0 +1,135 @@
+package cine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// kNodeHeader carries the node name of the dialing director in the CONNECT
+// request.
+const kNodeHeader = "Cine-Node"
+
+// WithTLS makes the director accept connections with server and connect to
+// other directors with client. If client.ServerName is empty, the certificate
+// of the other director is verified against the host of its node name, or
+// must have a URI SAN equal to the node name for unix: names.
+//
+// If server verifies client certificates, a connecting director must present
+// a certificate valid for the node name it claims: the host for host:port
+// names, or a URI SAN equal to the node name for unix: names.
+func WithTLS(server *tls.Config, client *tls.Config) Option {
+	return func(d *Director) {
+		d.config.tlsServer = server
+		d.config.tlsClient = client
+	}
+}
+
+// clientTLS upgrades a connection to node to TLS.
+func (d *Director) clientTLS(conn net.Conn, node string) (net.Conn, error) {
+	config := d.config.tlsClient
+	if config.ServerName == "" {
+		config = config.Clone()
+		if _, ok := unixSocketPath(node); ok && !config.InsecureSkipVerify {
+			// crypto/tls only verifies host names, the chain and the URI SAN
+			// are verified once the handshake is done
+			config.InsecureSkipVerify = true
+			verify := config.VerifyConnection
+			roots := config.RootCAs
+			config.VerifyConnection = func(state tls.ConnectionState) error {
+				if err := verifyServerNode(state, node, roots); err != nil {
+					return err
+				}
+				if verify != nil {
+					return verify(state)
+				}
+				return nil
+			}
+		} else {
+			config.ServerName = nodeHost(node)
+		}
+	}
+	tlsConn := tls.Client(conn, config)
+	if d.config.dialTimeout > 0 {
+		tlsConn.SetDeadline(time.Now().Add(d.config.dialTimeout))
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// nodeHost returns the host part of a node name.
+func nodeHost(node string) string {
+	if _, ok := unixSocketPath(node); ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		return node
+	}
+	return host
+}
+
+var (
+	errNodeNotInCertificate       = errors.New("node name does not match client certificate")
+	errNodeNotInServerCertificate = errors.New("node name does not match server certificate")
+)
+
+// verifyServerNode verifies the certificate chain of the director at node
+// with roots and that the certificate is valid for node.
+func verifyServerNode(state tls.ConnectionState, node string, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errNodeNotInServerCertificate
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	cert := state.PeerCertificates[0]
+	if _, err := cert.Verify(opts); err != nil {
+		return err
+	}
+	if !certificateMatchesNode(cert, node) {
+		return errNodeNotInServerCertificate
+	}
+	return nil
+}
+
+// peerNode returns the node name claimed by the director on the other end of
+// req and whether it was verified. If its client certificate was verified, the
+// node name must match it. Without a certificate anybody can claim any name.
+func peerNode(req *http.Request) (string, bool, error) {
+	node := req.Header.Get(kNodeHeader)
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return node, false, nil
+	}
+	cert := req.TLS.PeerCertificates[0]
+	if !certificateMatchesNode(cert, node) {
+		return "", false, errNodeNotInCertificate
+	}
+	return node, true, nil
+}
+
+func certificateMatchesNode(cert *x509.Certificate, node string) bool {
+	if node == "" {
+		return false
+	}
+	if path, ok := unixSocketPath(node); ok {
+		for _, uri := range cert.URIs {
+			if uri.Scheme == "unix" && uri.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+	return cert.VerifyHostname(nodeHost(node)) == nil
+}
@@ -0,0 +1,183 @@
+package cine
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cine test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert, key, pool}
+}
+
+// issue returns a certificate valid for the given IP addresses, or unix: node
+// names, usable by servers and clients.
+func (ca *testCA) issue(t *testing.T, ips ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "cine test node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, ip := range ips {
+		if uri, err := url.Parse(ip); err == nil && uri.Scheme == "unix" {
+			template.URIs = append(template.URIs, uri)
+			continue
+		}
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// tlsOption returns the TLS option of a director presenting cert.
+func (ca *testCA) tlsOption(cert tls.Certificate, requireClientCert bool) Option {
+	server := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    ca.pool,
+	}
+	if requireClientCert {
+		server.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	client := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      ca.pool,
+	}
+	return WithTLS(server, client)
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "127.0.0.1")
+	remoteD := mustNewDirector(t, "127.0.0.1:0", ca.tlsOption(cert, false))
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "127.0.0.1:0", ca.tlsOption(cert, false))
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	plain := newTestDirector(t)
+	if _, err := plain.Call(pid, (*Counter).Incr); err == nil {
+		t.Error("Expected a plaintext call to a TLS director to fail")
+	}
+
+	// The certificate of the other director must match its node name
+	otherCert := ca.issue(t, "10.0.0.1")
+	other := mustNewDirector(t, "127.0.0.1:0", ca.tlsOption(otherCert, false))
+	otherPid := other.StartActor(&Counter{})
+	defer other.Stop(otherPid)
+	if _, err := d.Call(otherPid, (*Counter).Incr); err == nil {
+		t.Error("Expected a certificate for another host to be rejected")
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "127.0.0.1")
+	remoteD := mustNewDirector(t, "127.0.0.1:0", ca.tlsOption(cert, true))
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, "127.0.0.1:0", ca.tlsOption(cert, true))
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	// A client without certificate is rejected
+	anonymous := mustNewDirector(t, "127.0.0.1:0", WithTLS(
+		&tls.Config{Certificates: []tls.Certificate{cert}},
+		&tls.Config{RootCAs: ca.pool}))
+	if _, err := anonymous.Call(pid, (*Counter).Incr); err == nil {
+		t.Error("Expected a client without certificate to be rejected")
+	}
+
+	// A client certificate does not allow to claim another node name
+	impostorCert := ca.issue(t, "10.0.0.1")
+	impostor := mustNewDirector(t, "127.0.0.1:0", WithTLS(
+		&tls.Config{Certificates: []tls.Certificate{cert}},
+		&tls.Config{Certificates: []tls.Certificate{impostorCert}, RootCAs: ca.pool}))
+	if _, err := impostor.Call(pid, (*Counter).Incr); err == nil {
+		t.Error("Expected a client certificate for another node to be rejected")
+	}
+}
+
+func TestTLSUnixSocket(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	remoteNode := "unix:" + dir + "/remote.sock"
+	localNode := "unix:" + dir + "/local.sock"
+	remoteD := mustNewDirector(t, remoteNode, ca.tlsOption(ca.issue(t, remoteNode), true))
+	pid := remoteD.StartActor(&Counter{})
+	defer remoteD.Stop(pid)
+
+	d := mustNewDirector(t, localNode, ca.tlsOption(ca.issue(t, localNode), true))
+	if _, err := d.Call(pid, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+
+	// The certificate of the other director must name its socket
+	otherNode := "unix:" + dir + "/other.sock"
+	other := mustNewDirector(t, otherNode, ca.tlsOption(ca.issue(t, localNode), false))
+	otherPid := other.StartActor(&Counter{})
+	defer other.Stop(otherPid)
+	if _, err := d.Call(otherPid, (*Counter).Incr); err == nil {
+		t.Error("Expected a certificate for another socket to be rejected")
+	}
+	// And be issued by a trusted CA
+	untrusted := newTestCA(t)
+	rogueNode := "unix:" + dir + "/rogue.sock"
+	rogue := mustNewDirector(t, rogueNode, untrusted.tlsOption(untrusted.issue(t, rogueNode), false))
+	roguePid := rogue.StartActor(&Counter{})
+	defer rogue.Stop(roguePid)
+	if _, err := d.Call(roguePid, (*Counter).Incr); err == nil {
+		t.Error("Expected a certificate of an unknown CA to be rejected")
+	}
+}
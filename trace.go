@@ -0,0 +1,296 @@
+package cine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// SpanContext identifies a span and its trace. It is carried in remote
+// requests so traces continue across directors. TraceId and SpanId are the hex
+// forms of 16 and 8 bytes ids, like in OpenTelemetry.
+type SpanContext struct {
+	TraceId string
+	SpanId  string
+}
+
+// IsValid returns true if c identifies a span.
+func (c SpanContext) IsValid() bool {
+	return c.TraceId != "" && c.SpanId != ""
+}
+
+// Span is an operation being traced.
+type Span interface {
+	Context() SpanContext
+	SetAttribute(key string, value string)
+	SetError(err error)
+	End()
+}
+
+// Tracer starts spans. Directors start a span for every Call and Cast, and
+// actors for every message they handle. A span without valid parent starts a
+// new trace.
+type Tracer interface {
+	StartSpan(name string, parent SpanContext) Span
+}
+
+// Span attributes set by directors and actors.
+const (
+	kSpanAttrPid    = "cine.pid"
+	kSpanAttrMethod = "cine.method"
+	kSpanAttrNode   = "cine.node"
+)
+
+// WithTracer traces the calls of the director and its actors with tracer.
+func WithTracer(tracer Tracer) Option {
+	return func(d *Director) {
+		d.tracer = tracer
+	}
+}
+
+// clockedTracer is implemented by tracers timing spans with the clock of the
+// director.
+type clockedTracer interface {
+	setClock(clock Clock)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying span. CallWithContext continues
+// the trace of the span of its context. The context given to a handler by
+// CallWithContext carries the span of the handler, so passing it on to the
+// next CallWithContext is enough to follow a request through actors and nodes.
+func ContextWithSpan(ctx context.Context, span SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, if any.
+func SpanFromContext(ctx context.Context) SpanContext {
+	span, _ := ctx.Value(spanKey{}).(SpanContext)
+	return span
+}
+
+// Span returns the span of the message being handled by the actor. It must be
+// called from the actor goroutine.
+func (r *Actor) Span() SpanContext {
+	if r.span == nil {
+		return SpanContext{}
+	}
+	return r.span.Context()
+}
+
+// noTracer is used when no Tracer is configured. Its spans pass the parent on
+// so traces started by other directors are not broken.
+type noTracer struct{}
+
+func (noTracer) StartSpan(name string, parent SpanContext) Span {
+	return noSpan{parent}
+}
+
+type noSpan struct {
+	parent SpanContext
+}
+
+func (s noSpan) Context() SpanContext                  { return s.parent }
+func (s noSpan) SetAttribute(key string, value string) {}
+func (s noSpan) SetError(err error)                    {}
+func (s noSpan) End()                                  {}
+
+// tracer returns the tracer of the director or noTracer for actors started
+// without a director.
+func (r *Actor) tracer() Tracer {
+	if r.director != nil && r.director.tracer != nil {
+		return r.director.tracer
+	}
+	return noTracer{}
+}
+
+func isTracing(tracer Tracer) bool {
+	_, ok := tracer.(noTracer)
+	return !ok
+}
+
+// startSpan starts the span of an operation of the director on pid.
+func (d *Director) startSpan(operation string, parent SpanContext, pid Pid, function interface{}) Span {
+	if !isTracing(d.tracer) {
+		return noSpan{parent}
+	}
+	method := functionName(reflect.ValueOf(function))
+	span := d.tracer.StartSpan(operation+" "+method, parent)
+	span.SetAttribute(kSpanAttrPid, pid.String())
+	span.SetAttribute(kSpanAttrMethod, method)
+	span.SetAttribute(kSpanAttrNode, d.nodeName)
+	return span
+}
+
+// endSpan ends span with the outcome of a call.
+func endSpan(span Span, err *DirectorError) {
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// startHandlerSpan starts the span of a message handled by the actor. If the
+// handler takes a context, the context given to it carries the span.
+func (r *Actor) startHandlerSpan(request *ActorCall, method string) Span {
+	span := r.tracer().StartSpan("handle "+method, request.span)
+	span.SetAttribute(kSpanAttrPid, r.pid.String())
+	span.SetAttribute(kSpanAttrMethod, method)
+	if r.director != nil {
+		span.SetAttribute(kSpanAttrNode, r.director.nodeName)
+	}
+	typ := request.Function.Type()
+	if typ.NumIn() > 1 && typ.In(1) == contextType && len(request.Args) > 1 {
+		if ctx, ok := request.Args[1].Interface().(context.Context); ok {
+			request.Args[1] = reflect.ValueOf(ContextWithSpan(ctx, span.Context()))
+		}
+	}
+	return span
+}
+
+// SpanData is a finished span, with the fields OpenTelemetry exporters need.
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	// Err is the error the span ended with, empty on success
+	Err string
+}
+
+// SpanExporter receives finished spans. Its methods mirror the span exporters
+// of OpenTelemetry, so one can be adapted with a thin wrapper converting
+// SpanData.
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// NewExportingTracer returns a tracer passing every span to exporter when it
+// ends. Spans are timed with the clock of the director the tracer is given to,
+// so directors with different clocks should not share a tracer.
+func NewExportingTracer(exporter SpanExporter) Tracer {
+	return &exportingTracer{exporter: exporter, clock: SystemClock}
+}
+
+type exportingTracer struct {
+	exporter SpanExporter
+	lock     sync.Mutex
+	clock    Clock
+}
+
+func (t *exportingTracer) setClock(clock Clock) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.clock = clock
+}
+
+func (t *exportingTracer) now() time.Time {
+	t.lock.Lock()
+	clock := t.clock
+	t.lock.Unlock()
+	return clock.Now()
+}
+
+func (t *exportingTracer) StartSpan(name string, parent SpanContext) Span {
+	traceId := parent.TraceId
+	if !parent.IsValid() {
+		traceId = randomId(16)
+		parent = SpanContext{}
+	}
+	return &exportedSpan{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Context:    SpanContext{TraceId: traceId, SpanId: randomId(8)},
+			Parent:     parent,
+			StartTime:  t.now(),
+			Attributes: make(map[string]string),
+		},
+	}
+}
+
+type exportedSpan struct {
+	tracer *exportingTracer
+	lock   sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+func (s *exportedSpan) Context() SpanContext {
+	return s.data.Context
+}
+
+func (s *exportedSpan) SetAttribute(key string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *exportedSpan) SetError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Err = err.Error()
+}
+
+func (s *exportedSpan) End() {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = s.tracer.now()
+	data := s.data
+	data.Attributes = make(map[string]string, len(s.data.Attributes))
+	for key, value := range s.data.Attributes {
+		data.Attributes[key] = value
+	}
+	s.lock.Unlock()
+	s.tracer.exporter.ExportSpans(context.Background(), []SpanData{data})
+}
+
+func randomId(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// SpanRecorder is a SpanExporter keeping spans in memory, e.g. for tests.
+type SpanRecorder struct {
+	lock  sync.Mutex
+	spans []SpanData
+}
+
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+func (r *SpanRecorder) ExportSpans(ctx context.Context, spans []SpanData) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *SpanRecorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the spans recorded so far in the order they ended.
+func (r *SpanRecorder) Spans() []SpanData {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
@@ -0,0 +1,184 @@
+package cine
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Hop forwards a call to the next actor of a chain
+type Hop struct {
+	Actor
+	director *Director
+	next     Pid
+}
+
+func (h *Hop) Forward(ctx context.Context) int {
+	if h.next == (Pid{}) {
+		return 1
+	}
+	r, err := h.director.CallWithContext(h.next, (*Hop).Forward, ctx)
+	if err != nil {
+		return 0
+	}
+	return r[0].(int) + 1
+}
+
+func (h *Hop) Crash() {
+	panic("crash")
+}
+
+func (h *Hop) ExposedMethods() []string {
+	return []string{"Forward"}
+}
+
+func (h *Hop) Terminate(errReason error) {
+}
+
+// startChain starts a Hop on every director, each forwarding to the next.
+func startChain(directors []*Director) Pid {
+	var next Pid
+	for i := len(directors) - 1; i >= 0; i-- {
+		next = directors[i].StartActor(&Hop{director: directors[i], next: next})
+	}
+	return next
+}
+
+func TestTraceAcrossDirectors(t *testing.T) {
+	recorder := NewSpanRecorder()
+	tracer := NewExportingTracer(recorder)
+	network := NewMemoryNetwork()
+	var directors []*Director
+	for _, name := range []string{"a:1", "b:1", "c:1"} {
+		directors = append(directors, mustNewDirector(t, name, WithTransport(network), WithTracer(tracer)))
+	}
+	pid := startChain(directors)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := directors[0].CallWithContext(pid, (*Hop).Forward, ctx)
+	if err != nil || r[0].(int) != 3 {
+		t.Fatalf("Expected 3 hops but got %v %v\n", r, err)
+	}
+
+	expected := []struct {
+		name string
+		node string
+	}{
+		{"call Forward", "a:1"},
+		{"handle Forward", "a:1"},
+		{"call Forward", "a:1"},
+		{"serve Forward", "b:1"},
+		{"handle Forward", "b:1"},
+		{"call Forward", "b:1"},
+		{"serve Forward", "c:1"},
+		{"handle Forward", "c:1"},
+	}
+	spans := recorder.Spans()
+	if len(spans) != len(expected) {
+		t.Fatalf("Expected %d spans but got %v\n", len(expected), spans)
+	}
+	// Spans end from the innermost one
+	for i := range expected {
+		span := spans[len(spans)-1-i]
+		if span.Name != expected[i].name || span.Attributes[kSpanAttrNode] != expected[i].node {
+			t.Errorf("Expected %v on %v but got %v\n", expected[i].name, expected[i].node, span)
+		}
+		if span.Attributes[kSpanAttrMethod] != "Forward" {
+			t.Errorf("Expected method Forward but got %v\n", span.Attributes)
+		}
+		if span.Context.TraceId != spans[0].Context.TraceId {
+			t.Errorf("Expected a single trace but got %v\n", span)
+		}
+		if i == 0 {
+			if span.Parent.IsValid() {
+				t.Errorf("Expected a root span but got %v\n", span)
+			}
+		} else if span.Parent != spans[len(spans)-i].Context {
+			t.Errorf("Expected %v to be the child of %v\n", span, spans[len(spans)-i])
+		}
+	}
+	if spans[len(spans)-2].Attributes[kSpanAttrPid] != pid.String() {
+		t.Errorf("Expected pid %v but got %v\n", pid, spans[len(spans)-2].Attributes)
+	}
+}
+
+func TestTraceThroughUntracedDirector(t *testing.T) {
+	recorder := NewSpanRecorder()
+	tracer := NewExportingTracer(recorder)
+	network := NewMemoryNetwork()
+	directors := []*Director{
+		mustNewDirector(t, "a:1", WithTransport(network), WithTracer(tracer)),
+		mustNewDirector(t, "b:1", WithTransport(network)),
+		mustNewDirector(t, "c:1", WithTransport(network), WithTracer(tracer)),
+	}
+	pid := startChain(directors)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	directors[0].CallWithContext(pid, (*Hop).Forward, ctx)
+
+	spans := recorder.Spans()
+	if len(spans) != 5 {
+		t.Fatalf("Expected spans of a and c but got %v\n", spans)
+	}
+	for _, span := range spans {
+		if span.Context.TraceId != spans[0].Context.TraceId {
+			t.Errorf("Expected a single trace but got %v\n", span)
+		}
+	}
+}
+
+func TestTracePanic(t *testing.T) {
+	recorder := NewSpanRecorder()
+	d := mustNewDirector(t, "127.0.0.1:0", WithTracer(NewExportingTracer(recorder)))
+	pid := d.StartActor(&Hop{director: d})
+	if _, err := d.Call(pid, (*Hop).Crash); err != ErrActorDied {
+		t.Errorf("Expected ErrActorDied but got %v\n", err)
+	}
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans but got %v\n", spans)
+	}
+	if spans[0].Name != "handle Crash" || spans[0].Err == "" {
+		t.Errorf("Expected the handler span to fail but got %v\n", spans[0])
+	}
+	if spans[1].Name != "call Crash" || spans[1].Err != ErrActorDied.Error() {
+		t.Errorf("Expected the call span to fail but got %v\n", spans[1])
+	}
+}
+
+func TestExportingTracerClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	setups := map[string]func(tracer Tracer) *Director{
+		"SetClock": func(tracer Tracer) *Director {
+			d := mustNewDirector(t, "127.0.0.1:0", WithTracer(tracer))
+			d.SetClock(clock)
+			return d
+		},
+		"tracer first": func(tracer Tracer) *Director {
+			return mustNewDirector(t, "127.0.0.1:0", WithTracer(tracer), WithClock(clock))
+		},
+		"clock first": func(tracer Tracer) *Director {
+			return mustNewDirector(t, "127.0.0.1:0", WithClock(clock), WithTracer(tracer))
+		},
+	}
+	for name, setup := range setups {
+		recorder := NewSpanRecorder()
+		d := setup(NewExportingTracer(recorder))
+		pid := d.StartActor(&Counter{})
+		d.Call(pid, (*Counter).Incr)
+		d.Stop(pid)
+
+		spans := recorder.Spans()
+		if len(spans) != 2 {
+			t.Fatalf("%s: Expected 2 spans but got %v\n", name, spans)
+		}
+		for _, span := range spans {
+			if !span.StartTime.Equal(clock.Now()) || !span.EndTime.Equal(clock.Now()) {
+				t.Errorf("%s: Expected span times from the director clock but got %v\n", name, span)
+			}
+		}
+	}
+}
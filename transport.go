@@ -0,0 +1,188 @@
+package cine
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport creates the connections between directors. The director serves
+// HTTP and rpc on top of them, so any reliable stream will do.
+type Transport interface {
+	Listen(addr string) (net.Listener, error)
+	Dial(addr string, timeout time.Duration) (net.Conn, error)
+}
+
+const kUnixPrefix = "unix:"
+
+// unixSocketPath returns the socket path of node names like
+// unix:/run/cine/node1.sock.
+func unixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, kUnixPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, kUnixPrefix), true
+}
+
+// TCPTransport is the default transport. Addresses starting with unix: are
+// Unix socket paths, so directors of the same host can skip TCP and protect
+// their traffic with file system permissions.
+type TCPTransport struct{}
+
+func (TCPTransport) Listen(addr string) (net.Listener, error) {
+	path, ok := unixSocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil && isStaleSocket(path) {
+		// Left behind by a process that did not shut down
+		os.Remove(path)
+		l, err = net.Listen("unix", path)
+	}
+	return l, err
+}
+
+func (TCPTransport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if path, ok := unixSocketPath(addr); ok {
+		return net.DialTimeout("unix", path, timeout)
+	}
+	return net.DialTimeout("tcp", addr, timeout)
+}
+
+// isStaleSocket returns true if path is a socket nobody listens on.
+func isStaleSocket(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return false
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return true
+	}
+	conn.Close()
+	return false
+}
+
+var (
+	errConnectionRefused = errors.New("connection refused")
+	errAddressInUse      = errors.New("address already in use")
+	errListenerClosed    = errors.New("use of closed network connection")
+	errDialTimeout       = errors.New("i/o timeout")
+)
+
+// MemoryNetwork is a Transport connecting directors of the same process with
+// in-memory pipes. Addresses are plain names; a port 0 is replaced by a free
+// port as with TCP.
+type MemoryNetwork struct {
+	lock      sync.Mutex
+	listeners map[string]*memoryListener
+	nextPort  int
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		listeners: make(map[string]*memoryListener),
+		nextPort:  1,
+	}
+}
+
+func (n *MemoryNetwork) Listen(addr string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if port == "0" {
+		for {
+			addr = net.JoinHostPort(host, strconv.Itoa(n.nextPort))
+			n.nextPort += 1
+			if _, ok := n.listeners[addr]; !ok {
+				break
+			}
+		}
+	}
+	if _, ok := n.listeners[addr]; ok {
+		return nil, &net.OpError{Op: "listen", Net: "memory", Addr: memoryAddr(addr), Err: errAddressInUse}
+	}
+	l := &memoryListener{
+		network: n,
+		addr:    memoryAddr(addr),
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+	n.listeners[addr] = l
+	return l, nil
+}
+
+func (n *MemoryNetwork) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	n.lock.Lock()
+	l, ok := n.listeners[addr]
+	n.lock.Unlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "memory", Addr: memoryAddr(addr), Err: errConnectionRefused}
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "dial", Net: "memory", Addr: memoryAddr(addr), Err: errConnectionRefused}
+	case <-expired:
+		return nil, &net.OpError{Op: "dial", Net: "memory", Addr: memoryAddr(addr), Err: errDialTimeout}
+	}
+}
+
+type memoryAddr string
+
+func (a memoryAddr) Network() string {
+	return "memory"
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
+
+type memoryListener struct {
+	network   *MemoryNetwork
+	addr      memoryAddr
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "accept", Net: "memory", Addr: l.addr, Err: errListenerClosed}
+	}
+}
+
+func (l *memoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.network.lock.Lock()
+		delete(l.network.listeners, string(l.addr))
+		l.network.lock.Unlock()
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
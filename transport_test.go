@@ -0,0 +1,117 @@
+package cine
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestMemoryNetwork(t *testing.T) {
+	network := NewMemoryNetwork()
+	l, err := network.Listen("node:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	addr := l.Addr().String()
+	if addr == "node:0" {
+		t.Error("Expected a port to be assigned")
+	}
+	if _, err := network.Listen(addr); err == nil {
+		t.Error("Expected an error for an address in use")
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4)
+		conn.Read(buf)
+		conn.Write(buf)
+	}()
+	conn, err := network.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := conn.Read(buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Expected ping but got %q, %v\n", buf, err)
+	}
+	conn.Close()
+
+	l.Close()
+	if _, err := network.Dial(addr, time.Second); err == nil {
+		t.Error("Expected dialing a closed listener to fail")
+	}
+	if _, err := network.Listen(addr); err != nil {
+		t.Errorf("Expected the address to be released but got %v\n", err)
+	}
+}
+
+func TestMemoryNetworkDirectors(t *testing.T) {
+	network := NewMemoryNetwork()
+	var directors []*Director
+	var pids []Pid
+	for i := 0; i < 100; i++ {
+		d := mustNewDirector(t, fmt.Sprintf("node%d:9000", i), WithTransport(network))
+		directors = append(directors, d)
+		pids = append(pids, d.StartActor(&Phonebook{Actor{}, make(map[string]int)}))
+	}
+
+	// Every director adds its number to the phonebook of the next one
+	for i, d := range directors {
+		next := pids[(i+1)%len(pids)]
+		if _, err := d.Call(next, (*Phonebook).Add, d.NodeName(), i); err != nil {
+			t.Fatalf("Expected no error but got %v\n", err)
+		}
+	}
+	for i := range directors {
+		prev := directors[(i+len(directors)-1)%len(directors)]
+		r, err := prev.Call(pids[i], (*Phonebook).Lookup, prev.NodeName())
+		if err != nil {
+			t.Fatalf("Expected no error but got %v\n", err)
+		}
+		if expected := (i + len(directors) - 1) % len(directors); r[0].(int) != expected {
+			t.Errorf("Expected %d but got %v\n", expected, r[0])
+		}
+	}
+
+	for _, d := range directors {
+		d.Shutdown(context.Background())
+	}
+}
+
+func TestUnixSocketDirectors(t *testing.T) {
+	dir := t.TempDir()
+	a := mustNewDirector(t, "unix:"+dir+"/a.sock")
+	b := mustNewDirector(t, "unix:"+dir+"/b.sock")
+	if _, err := NewDirector(b.NodeName()); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+
+	pid := b.StartActor(&Counter{})
+	r, err := a.Call(pid, (*Counter).Incr)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(int) != 1 {
+		t.Errorf("Expected 1 but got %v\n", r[0])
+	}
+	a.Shutdown(context.Background())
+	b.Shutdown(context.Background())
+
+	// A socket left behind by a crashed process is replaced
+	path := dir + "/stale.sock"
+	l, lerr := net.Listen("unix", path)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	mustNewDirector(t, "unix:"+path).Shutdown(context.Background())
+}
@@ -0,0 +1,260 @@
+package cine
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Identity addresses a virtual actor. A virtual actor always exists
+// conceptually; it is activated on demand by the first message and deactivated
+// when idle.
+type Identity struct {
+	Kind string
+	Key  string
+}
+
+func (id Identity) String() string {
+	return id.Kind + "/" + id.Key
+}
+
+// Kind describes a type of virtual actor.
+type Kind struct {
+	Name string
+	// Factory creates the instance of key, which may come from another node.
+	// Activations fail with ErrInvalidArgs if it panics or returns nil.
+	Factory func(key string) ActorImplementor
+	// IdleTimeout deactivates instances that did not receive a message for the
+	// duration. Zero keeps instances alive until stopped.
+	IdleTimeout time.Duration
+}
+
+// Placement decides which node hosts the activation of an identity. Every
+// director of a cluster must use an equivalent placement.
+type Placement interface {
+	// Place returns the node name that should host id.
+	Place(id Identity) string
+}
+
+// LocalPlacement activates every identity on the calling director. It is the
+// default placement.
+type LocalPlacement struct{}
+
+func (LocalPlacement) Place(id Identity) string {
+	return ""
+}
+
+// HashPlacement spreads identities over a fixed set of nodes using rendezvous
+// hashing, so only the identities of a removed node move elsewhere.
+type HashPlacement struct {
+	Nodes []string
+}
+
+func (p HashPlacement) Place(id Identity) string {
+	var best string
+	var bestScore uint64
+	for _, node := range p.Nodes {
+		h := fnv.New64a()
+		h.Write([]byte(node))
+		h.Write([]byte{0})
+		h.Write([]byte(id.String()))
+		if score := h.Sum64(); best == "" || score > bestScore {
+			best, bestScore = node, score
+		}
+	}
+	return best
+}
+
+// virtualActors holds the virtual actor state of a director. activations is
+// the directory of identities hosted by this node and cache remembers the pids
+// of identities hosted elsewhere.
+type virtualActors struct {
+	lock        sync.Mutex
+	kinds       map[string]Kind
+	placement   Placement
+	activations map[Identity]Pid
+	cache       map[Identity]Pid
+}
+
+type ActivateRequest struct {
+	Identity Identity
+}
+
+type ActivateResponse struct {
+	Err *DirectorError
+	Pid Pid
+}
+
+// RegisterKind registers a kind of virtual actor. Every node that may host the
+// kind according to the placement must register it.
+func (d *Director) RegisterKind(kind Kind) {
+	d.virtual.lock.Lock()
+	defer d.virtual.lock.Unlock()
+	d.virtual.kinds[kind.Name] = kind
+}
+
+// SetPlacement sets the placement strategy of virtual actors.
+func (d *Director) SetPlacement(placement Placement) {
+	d.virtual.lock.Lock()
+	defer d.virtual.lock.Unlock()
+	d.virtual.placement = placement
+}
+
+// Activate returns the pid of the current activation of id, activating it on
+// the node chosen by the placement if necessary.
+func (d *Director) Activate(id Identity) (Pid, *DirectorError) {
+	d.virtual.lock.Lock()
+	node := d.virtual.placement.Place(id)
+	if node == "" || node == d.nodeName {
+		d.virtual.lock.Unlock()
+		return d.activateLocal(id)
+	}
+	pid, ok := d.virtual.cache[id]
+	d.virtual.lock.Unlock()
+	if ok {
+		return pid, nil
+	}
+
+	pid, err := d.activateRemote(node, id)
+	if err != nil {
+		return Pid{}, err
+	}
+	d.virtual.lock.Lock()
+	d.virtual.cache[id] = pid
+	d.virtual.lock.Unlock()
+	return pid, nil
+}
+
+// activateLocal returns the local activation of id, starting it if
+// necessary. ErrInvalidArgs is returned if the factory panics or returns nil.
+func (d *Director) activateLocal(id Identity) (Pid, *DirectorError) {
+	if d.isShutDown() {
+		return Pid{}, ErrShutdown
+	}
+	d.virtual.lock.Lock()
+	if pid, ok := d.currentActivation(id); ok {
+		d.virtual.lock.Unlock()
+		return pid, nil
+	}
+	kind, ok := d.virtual.kinds[id.Kind]
+	d.virtual.lock.Unlock()
+	if !ok {
+		return Pid{}, ErrKindNotFound
+	}
+
+	// Keys may come from other nodes, the factory is called like the ones of
+	// SpawnOn and outside of the lock
+	actorImpl, ok := d.build(id.Kind, func(args ...interface{}) ActorImplementor {
+		return kind.Factory(id.Key)
+	}, nil)
+	if !ok {
+		return Pid{}, ErrInvalidArgs
+	}
+	actor := actorImpl.getActor()
+	actor.identity = id
+	if kind.IdleTimeout > 0 {
+		actor.SetIdleTimeout(kind.IdleTimeout)
+	}
+
+	d.virtual.lock.Lock()
+	defer d.virtual.lock.Unlock()
+	if pid, ok := d.currentActivation(id); ok {
+		// Activated concurrently, the new instance was never started
+		return pid, nil
+	}
+	pid := d.StartActor(actorImpl)
+	d.virtual.activations[id] = pid
+	return pid, nil
+}
+
+// currentActivation returns the running activation of id. Must be called
+// within d.virtual.lock critical section.
+func (d *Director) currentActivation(id Identity) (Pid, bool) {
+	pid, ok := d.virtual.activations[id]
+	if !ok {
+		return Pid{}, false
+	}
+	if _, err := d.localActorFromPid(pid); err != nil {
+		delete(d.virtual.activations, id)
+		return Pid{}, false
+	}
+	return pid, true
+}
+
+func (d *Director) activateRemote(node string, id Identity) (Pid, *DirectorError) {
+	var resp ActivateResponse
+	if err := d.remoteCall(node, "HandleActivate", ActivateRequest{id}, &resp); err != nil {
+		return Pid{}, err
+	}
+	if resp.Err != nil {
+		return Pid{}, canonicalError(resp.Err)
+	}
+	return resp.Pid, nil
+}
+
+// forgetIdentity drops a cached pid that turned out to be stale.
+func (d *Director) forgetIdentity(id Identity, pid Pid) {
+	d.virtual.lock.Lock()
+	defer d.virtual.lock.Unlock()
+	if cached, ok := d.virtual.cache[id]; ok && cached == pid {
+		delete(d.virtual.cache, id)
+	}
+}
+
+// deactivated removes a terminated actor from the directory.
+func (d *Director) deactivated(id Identity, pid Pid) {
+	d.virtual.lock.Lock()
+	defer d.virtual.lock.Unlock()
+	if current, ok := d.virtual.activations[id]; ok && current == pid {
+		delete(d.virtual.activations, id)
+	}
+}
+
+// CallIdentity calls the function on the virtual actor id, activating it if
+// necessary. A call that reaches a deactivated instance is retried once on a
+// new activation.
+func (d *Director) CallIdentity(id Identity, function interface{}, args ...interface{}) ([]interface{}, *DirectorError) {
+	var err *DirectorError
+	for attempt := 0; attempt < 2; attempt++ {
+		var pid Pid
+		pid, err = d.Activate(id)
+		if err != nil {
+			return nil, err
+		}
+		var ret []interface{}
+		ret, err = d.Call(pid, function, args...)
+		if err == ErrActorNotFound || err == ErrActorStop {
+			d.forgetIdentity(id, pid)
+			continue
+		}
+		return ret, err
+	}
+	return nil, err
+}
+
+// CastIdentity casts the function to the virtual actor id, activating it if
+// necessary.
+func (d *Director) CastIdentity(id Identity, done chan *ActorCall, function interface{}, args ...interface{}) {
+	pid, err := d.Activate(id)
+	if err != nil {
+		return
+	}
+	d.Cast(pid, done, function, args...)
+}
+
+// kActivateMethod is the method name remote activations are authorized with.
+const kActivateMethod = "Activate"
+
+func (d *DirectorApi) HandleActivate(r ActivateRequest, reply *ActivateResponse) error {
+	if err := d.authorize(Pid{NodeName: d.director.nodeName}, kActivateMethod); err != nil {
+		reply.Err = err
+		return nil
+	}
+	pid, err := d.director.activateLocal(r.Identity)
+	if err != nil {
+		reply.Err = err
+		return nil
+	}
+	reply.Pid = pid
+	return nil
+}
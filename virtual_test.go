@@ -0,0 +1,186 @@
+package cine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type Counter struct {
+	Actor
+	key   string
+	count int
+}
+
+func (c *Counter) Incr() int {
+	c.count += 1
+	return c.count
+}
+
+func (c *Counter) Key() string {
+	return c.key
+}
+
+func (c *Counter) ExposedMethods() []string {
+	return []string{"Incr", "Key"}
+}
+
+func (c *Counter) Terminate(errReason error) {
+}
+
+func newCounter(key string) ActorImplementor {
+	return &Counter{key: key}
+}
+
+func TestHashPlacement(t *testing.T) {
+	p := HashPlacement{Nodes: []string{"a:1", "b:1", "c:1"}}
+	seen := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		id := Identity{"counter", fmt.Sprint(i)}
+		node := p.Place(id)
+		if node != p.Place(id) {
+			t.Fatalf("Placement of %v is not stable\n", id)
+		}
+		seen[node] += 1
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected identities on all 3 nodes but got %v\n", seen)
+	}
+
+	// Removing a node only moves the identities it hosted
+	q := HashPlacement{Nodes: []string{"a:1", "b:1"}}
+	for i := 0; i < 300; i++ {
+		id := Identity{"counter", fmt.Sprint(i)}
+		if before := p.Place(id); before != "c:1" && before != q.Place(id) {
+			t.Errorf("%v moved from %v to %v\n", id, before, q.Place(id))
+		}
+	}
+}
+
+func TestVirtualActorLocal(t *testing.T) {
+	d := newTestDirector(t)
+	clock := NewFakeClock(time.Now())
+	d.SetClock(clock)
+	d.RegisterKind(Kind{Name: "counter", Factory: newCounter, IdleTimeout: time.Minute})
+
+	id := Identity{"counter", "jane"}
+	for i := 1; i <= 3; i++ {
+		r, err := d.CallIdentity(id, (*Counter).Incr)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v\n", err)
+		}
+		if r[0].(int) != i {
+			t.Errorf("Expected %d but got %v\n", i, r[0])
+		}
+	}
+	pid, _ := d.Activate(id)
+
+	// Deactivate by idleness, the next call activates a fresh instance
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	waitFor(t, "deactivation", func() bool {
+		_, err := d.localActorFromPid(pid)
+		return err != nil
+	})
+	r, err := d.CallIdentity(id, (*Counter).Incr)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(int) != 1 {
+		t.Errorf("Expected reactivated counter to start over but got %v\n", r[0])
+	}
+	if newPid, _ := d.Activate(id); newPid == pid {
+		t.Errorf("Expected a new activation but got the same pid %v\n", pid)
+	}
+
+	if _, err := d.CallIdentity(Identity{"unknown", "x"}, (*Counter).Incr); err != ErrKindNotFound {
+		t.Errorf("Expected ErrKindNotFound but got %v\n", err)
+	}
+}
+
+func TestVirtualActorPlacement(t *testing.T) {
+	var directors []*Director
+	var nodes []string
+	for i := 0; i < 3; i++ {
+		d := newTestDirector(t)
+		d.RegisterKind(Kind{Name: "counter", Factory: newCounter})
+		directors = append(directors, d)
+		nodes = append(nodes, d.nodeName)
+	}
+	placement := HashPlacement{Nodes: nodes}
+	for _, d := range directors {
+		d.SetPlacement(placement)
+	}
+
+	for i := 0; i < 10; i++ {
+		id := Identity{"counter", fmt.Sprint(i)}
+		owner := placement.Place(id)
+		for j, d := range directors {
+			r, err := d.CallIdentity(id, (*Counter).Incr)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v\n", err)
+			}
+			if r[0].(int) != j+1 {
+				t.Errorf("Expected %v to be called %d times but got %v\n", id, j+1, r[0])
+			}
+			pid, _ := d.Activate(id)
+			if pid.NodeName != owner {
+				t.Errorf("Expected %v on %v but got %v\n", id, owner, pid)
+			}
+		}
+	}
+
+	// Stop an activation behind the callers back; the cached pid is stale and
+	// the call is transparently retried on a new activation.
+	id := Identity{"counter", "0"}
+	owner := placement.Place(id)
+	var caller *Director
+	for _, d := range directors {
+		if d.nodeName != owner {
+			caller = d
+		}
+	}
+	pid, _ := caller.Activate(id)
+	caller.Stop(pid)
+	waitFor(t, "stop", func() bool {
+		for _, d := range directors {
+			if d.nodeName == owner {
+				_, err := d.localActorFromPid(pid)
+				return err != nil
+			}
+		}
+		return false
+	})
+	r, err := caller.CallIdentity(id, (*Counter).Key)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v\n", err)
+	}
+	if r[0].(string) != "0" {
+		t.Errorf("Expected key 0 but got %v\n", r[0])
+	}
+}
+
+func TestActivatePanickingKind(t *testing.T) {
+	network := NewMemoryNetwork()
+	remoteD := mustNewDirector(t, "remote:1", WithTransport(network))
+	remoteD.RegisterKind(Kind{Name: "picky", Factory: func(key string) ActorImplementor {
+		if key == "nil" {
+			var counter *Counter
+			return counter
+		}
+		panic("bad key " + key)
+	}})
+
+	d := mustNewDirector(t, "local:1", WithTransport(network))
+	d.SetPlacement(HashPlacement{Nodes: []string{"remote:1"}})
+	for _, key := range []string{"boom", "nil"} {
+		if _, err := d.Activate(Identity{"picky", key}); err != ErrInvalidArgs {
+			t.Errorf("Expected ErrInvalidArgs but got %v\n", err)
+		}
+	}
+	// The node survived
+	remoteD.RegisterKind(Kind{Name: "counter", Factory: newCounter})
+	if _, err := d.CallIdentity(Identity{"counter", "a"}, (*Counter).Incr); err != nil {
+		t.Errorf("Expected no error but got %v\n", err)
+	}
+}